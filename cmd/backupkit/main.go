@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/dev-tams/backupkit/internal/app"
 	"github.com/dev-tams/backupkit/internal/config"
@@ -26,13 +27,29 @@ func main() {
 			{
 				Name:  "backup",
 				Usage: "run a backup of the configured project",
-				Flags: backupOrRestoreFlags(),
+				Flags: append(
+					backupOrRestoreFlags(),
+					&cli.BoolFlag{
+						Name:  "stdout",
+						Usage: "write one database's backup to stdout instead of storage (logs go to stderr; no retention or notifications)",
+					},
+					&cli.StringFlag{
+						Name:  "db",
+						Usage: "database name from config for --stdout (optional; defaults to first database)",
+					},
+				),
 				Action: func(c *cli.Context) error {
 					cfg, err := loadValidatedConfig(c.String("config"))
 					if err != nil {
 						return err
 					}
 
+					if c.Bool("stdout") {
+						return app.RunBackupToStdout(c.Context, cfg, c.String("db"), c.Bool("verbose"))
+					}
+					if c.String("db") != "" {
+						return fmt.Errorf("backup: --db is only supported with --stdout")
+					}
 					return app.RunBackup(c.Context, cfg, c.Bool("verbose"))
 				},
 			},
@@ -48,7 +65,7 @@ func main() {
 					&cli.StringFlag{
 						Name:     "from",
 						Required: true,
-						Usage:    "path to backup file to restore",
+						Usage:    "path to backup file to restore, or - to read stdin",
 					},
 					&cli.BoolFlag{
 						Name:  "clean",
@@ -62,6 +79,92 @@ func main() {
 						Name:  "allow-sql-fallback",
 						Usage: "if decoded stream is plain SQL, restore with psql instead of pg_restore",
 					},
+					&cli.StringSliceFlag{
+						Name:  "schema",
+						Usage: "restore only objects in this schema (repeatable; pg_restore --schema)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "table",
+						Usage: "restore only this table (repeatable; pg_restore --table)",
+					},
+					&cli.BoolFlag{
+						Name:  "schema-only",
+						Usage: "restore only object definitions, not data",
+					},
+					&cli.BoolFlag{
+						Name:  "data-only",
+						Usage: "restore only data, not object definitions",
+					},
+					&cli.BoolFlag{
+						Name:  "list",
+						Usage: "print the archive table of contents (pg_restore --list) and exit without restoring",
+					},
+					&cli.StringFlag{
+						Name:  "use-list",
+						Usage: "restore only the TOC entries in this file (edited output of --list)",
+					},
+					&cli.BoolFlag{
+						Name:  "no-owner",
+						Usage: "do not restore object ownership (pg_restore --no-owner)",
+					},
+					&cli.BoolFlag{
+						Name:  "no-privileges",
+						Usage: "do not restore GRANT/REVOKE privileges (pg_restore --no-privileges)",
+					},
+					&cli.StringFlag{
+						Name:  "role",
+						Usage: "role to SET ROLE to before restoring",
+					},
+					&cli.StringSliceFlag{
+						Name:  "role-map",
+						Usage: "rename a role in OWNER TO/GRANT/REVOKE as old=new (repeatable; restores via psql)",
+					},
+					&cli.BoolFlag{
+						Name:  "i-understand",
+						Usage: "confirm a restore into a database marked protected in config",
+					},
+					&cli.BoolFlag{
+						Name:  "snapshot",
+						Usage: "back up the target database before restoring so the restore can be undone",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "sniff the backup, check tools and print the restore plan without touching the database",
+					},
+					&cli.BoolFlag{
+						Name:  "progress",
+						Usage: "print periodic restore progress (bytes, throughput, ETA, current TOC entry) to stderr",
+					},
+					&cli.StringFlag{
+						Name:  "progress-format",
+						Value: "text",
+						Usage: "progress output format: text or json (one JSON object per line)",
+					},
+					&cli.DurationFlag{
+						Name:  "progress-interval",
+						Value: 5 * time.Second,
+						Usage: "how often to print restore progress",
+					},
+					&cli.IntFlag{
+						Name:  "jobs",
+						Usage: "run pg_restore with N parallel workers (decodes the backup into a temp file first)",
+					},
+					&cli.StringFlag{
+						Name:  "temp-dir",
+						Usage: "directory for the decoded temp file used by --jobs (defaults to the system temp dir)",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "where engines that restore to files write: redis RDB file (default <db>.rdb), files target directory, physical postgres data directory (must be empty)",
+					},
+					&cli.StringFlag{
+						Name:  "target-time",
+						Usage: "physical postgres restores with wal_archive: replay WAL up to this RFC 3339 time (e.g. 2026-03-01T14:30:00Z)",
+					},
+					&cli.StringFlag{
+						Name:  "globals",
+						Usage: "postgres globals backup (<db>/globals/<ts>.sql...) to apply with psql before the database restore",
+					},
 				),
 				Action: func(c *cli.Context) error {
 					cfg, err := loadValidatedConfig(c.String("config"))
@@ -69,16 +172,128 @@ func main() {
 						return err
 					}
 
-					return app.RunRestore(
-						c.Context,
-						cfg,
-						c.String("db"),
-						c.String("from"),
-						c.Bool("verbose"),
-						c.Bool("clean"),
-						c.Bool("strict-sniff"),
-						c.Bool("allow-sql-fallback"),
-					)
+					progress := ""
+					if c.Bool("progress") {
+						progress = c.String("progress-format")
+					}
+
+					return app.RunRestore(c.Context, cfg, app.RestoreOptions{
+						DBName:           c.String("db"),
+						FromPath:         c.String("from"),
+						Verbose:          c.Bool("verbose"),
+						Clean:            c.Bool("clean"),
+						StrictSniff:      c.Bool("strict-sniff"),
+						AllowSQLFallback: c.Bool("allow-sql-fallback"),
+						Schemas:          c.StringSlice("schema"),
+						Tables:           c.StringSlice("table"),
+						SchemaOnly:       c.Bool("schema-only"),
+						DataOnly:         c.Bool("data-only"),
+						List:             c.Bool("list"),
+						UseList:          c.String("use-list"),
+						NoOwner:          c.Bool("no-owner"),
+						NoPrivileges:     c.Bool("no-privileges"),
+						Role:             c.String("role"),
+						RoleMap:          c.StringSlice("role-map"),
+						IUnderstand:      c.Bool("i-understand"),
+						Snapshot:         c.Bool("snapshot"),
+						DryRun:           c.Bool("dry-run"),
+						Progress:         progress,
+						ProgressInterval: c.Duration("progress-interval"),
+						Jobs:             c.Int("jobs"),
+						TempDir:          c.String("temp-dir"),
+						OutPath:          c.String("out"),
+						Globals:          c.String("globals"),
+						TargetTime:       c.String("target-time"),
+						ConfigPath:       c.String("config"),
+					})
+				},
+			},
+			{
+				Name:  "decode",
+				Usage: "decrypt and decompress a backup into a plain .dump/.sql without touching the database",
+				Flags: append(
+					backupOrRestoreFlags(),
+					&cli.StringFlag{
+						Name:  "db",
+						Usage: "database name from config whose encryption password to use (optional; defaults to first database)",
+					},
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "path to a local backup file, or - to read stdin",
+					},
+					&cli.StringFlag{
+						Name:  "storage",
+						Usage: "storage name from config to read the backup from (use with --key)",
+					},
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "backup key within --storage, e.g. app_db/20260218_120000.000000000Z.dump.gz.enc",
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Value:   "-",
+						Usage:   "output file, or - for stdout",
+					},
+				),
+				Action: func(c *cli.Context) error {
+					cfg, err := loadValidatedConfig(c.String("config"))
+					if err != nil {
+						return err
+					}
+
+					return app.RunDecode(c.Context, cfg, app.DecodeOptions{
+						DBName:   c.String("db"),
+						FromPath: c.String("from"),
+						Storage:  c.String("storage"),
+						Key:      c.String("key"),
+						OutPath:  c.String("out"),
+						Verbose:  c.Bool("verbose"),
+					})
+				},
+			},
+			{
+				Name:      "wal-push",
+				Usage:     "archive a WAL file; use as postgres archive_command: backupkit wal-push -c <config> --db <name> %p %f",
+				ArgsUsage: "<path> <name>",
+				Flags: append(
+					backupOrRestoreFlags(),
+					&cli.StringFlag{
+						Name:  "db",
+						Usage: "database name from config with postgres.wal_archive (optional; defaults to first database)",
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("wal-push: expected <path> <name>, got %d arguments", c.NArg())
+					}
+					cfg, err := loadValidatedConfig(c.String("config"))
+					if err != nil {
+						return err
+					}
+					return app.RunWALPush(c.Context, cfg, c.String("db"), c.Args().Get(0), c.Args().Get(1), c.Bool("verbose"))
+				},
+			},
+			{
+				Name:      "wal-fetch",
+				Usage:     "restore an archived WAL file; use as postgres restore_command: backupkit wal-fetch -c <config> --db <name> %f %p",
+				ArgsUsage: "<name> <dest>",
+				Flags: append(
+					backupOrRestoreFlags(),
+					&cli.StringFlag{
+						Name:  "db",
+						Usage: "database name from config with postgres.wal_archive (optional; defaults to first database)",
+					},
+				),
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("wal-fetch: expected <name> <dest>, got %d arguments", c.NArg())
+					}
+					cfg, err := loadValidatedConfig(c.String("config"))
+					if err != nil {
+						return err
+					}
+					return app.RunWALFetch(c.Context, cfg, c.String("db"), c.Args().Get(0), c.Args().Get(1), c.Bool("verbose"))
 				},
 			},
 			{
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func commandTestConfig(store string, cmd *config.CommandConfig) *config.Config {
+	return &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name:    "etcd",
+			Type:    "command",
+			Command: cmd,
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+			},
+		}},
+	}
+}
+
+func TestCommandBackupAndRestore(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	fakeTool(t, bin, "snapshot-tool", `echo "$@" > `+work+`/backup.args
+echo "$SNAP_TOKEN" > `+work+`/backup.env
+echo "progress on stderr" >&2
+printf 'opaque-snapshot'
+`)
+	fakeTool(t, bin, "load-tool", `echo "$@" > `+work+`/restore.args
+echo "$SNAP_TOKEN" > `+work+`/restore.env
+cat > `+work+`/restored
+`)
+
+	cfg := commandTestConfig(t.TempDir(), &config.CommandConfig{
+		Backup:  []string{"snapshot-tool", "save", "-"},
+		Restore: []string{"load-tool", "--from-stdin"},
+		Env:     []string{"SNAP_TOKEN=tok"},
+	})
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".bin.gz") {
+		t.Fatalf("expected .bin.gz key, got %s", results[0].Key)
+	}
+	if got := readFile(t, filepath.Join(work, "backup.args")); got != "save -\n" {
+		t.Fatalf("unexpected backup args %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "backup.env")); got != "tok\n" {
+		t.Fatalf("expected env passed to backup command, got %q", got)
+	}
+
+	decoded := filepath.Join(t.TempDir(), "etcd.bin")
+	if err := RunDecode(context.Background(), cfg, DecodeOptions{DBName: "etcd", FromPath: results[0].Dest, OutPath: decoded}); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := readFile(t, decoded); got != "opaque-snapshot" {
+		t.Fatalf("unexpected decoded stream %q", got)
+	}
+
+	opts := RestoreOptions{DBName: "etcd", FromPath: results[0].Dest, StrictSniff: true, DryRun: true}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "restored")); err == nil {
+		t.Fatalf("dry run must not run the restore command")
+	}
+
+	opts.DryRun = false
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "restored")); got != "opaque-snapshot" {
+		t.Fatalf("unexpected restored stream %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.env")); got != "tok\n" {
+		t.Fatalf("expected env passed to restore command, got %q", got)
+	}
+}
+
+func TestCommandBackupFailureIncludesStderr(t *testing.T) {
+	bin := withFakeBin(t)
+	fakeTool(t, bin, "snapshot-tool", `echo "cluster unreachable" >&2
+exit 3
+`)
+	cfg := commandTestConfig(t.TempDir(), &config.CommandConfig{Backup: []string{"snapshot-tool"}})
+	_, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "cluster unreachable") {
+		t.Fatalf("expected stderr in backup error, got %v", err)
+	}
+}
+
+func TestCommandRestoreRequiresRestoreArgv(t *testing.T) {
+	withFakeBin(t)
+	from := filepath.Join(t.TempDir(), "etcd.bin")
+	if err := os.WriteFile(from, []byte("opaque"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := commandTestConfig(t.TempDir(), &config.CommandConfig{Backup: []string{"snapshot-tool"}})
+	err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "etcd", FromPath: from})
+	if err == nil || !strings.Contains(err.Error(), "no command.restore argv") {
+		t.Fatalf("expected missing restore argv error, got %v", err)
+	}
+}
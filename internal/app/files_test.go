@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestFilesBackupAndRestoreIntoDirectory(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "site")
+	if err := os.MkdirAll(filepath.Join(src, "public"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "public", "index.html"), []byte("<h1>hi</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:  "site",
+			Type:  "files",
+			Files: &config.FilesConfig{Paths: []string{src}},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+		}},
+	}
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".tar.gz.enc") {
+		t.Fatalf("expected .tar.gz.enc key, got %s", results[0].Key)
+	}
+
+	out := t.TempDir()
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "site", FromPath: results[0].Dest, OutPath: out, StrictSniff: true})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got := readFile(t, filepath.Join(out, filepath.FromSlash(strings.TrimPrefix(filepath.ToSlash(src), "/")), "public", "index.html"))
+	if got != "<h1>hi</h1>" {
+		t.Fatalf("unexpected restored file %q", got)
+	}
+
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "site", FromPath: results[0].Dest})
+	if err == nil || !strings.Contains(err.Error(), "--out <dir> is required") {
+		t.Fatalf("expected --out requirement, got %v", err)
+	}
+}
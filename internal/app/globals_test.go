@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestPostgresGlobalsBackupAndRestore(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	fakeTool(t, bin, "pg_dump", `printf 'PGDMP-archive'
+`)
+	fakeTool(t, bin, "pg_dumpall", `echo "$@" > `+work+`/dumpall.args
+printf -- '--\n-- PostgreSQL database cluster dump\n--\nCREATE ROLE app;\nALTER ROLE app WITH LOGIN;\n'
+`)
+	fakeTool(t, bin, "psql", `case "$*" in *"SHOW server_version"*) echo 16.2; exit 0 ;; esac
+echo psql >> `+work+`/order
+cat > `+work+`/globals.sql
+echo 'ERROR:  role "postgres" already exists' >&2
+`)
+	fakeTool(t, bin, "pg_restore", `case "$*" in *--list*) exit 0 ;; --version) echo "pg_restore (PostgreSQL) 16.4"; exit 0 ;; esac
+echo pg_restore >> `+work+`/order
+cat > /dev/null
+`)
+
+	store := t.TempDir()
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "app",
+			Type:       "postgres",
+			Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "postgres", Password: "pw"},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+			Retention: config.RetentionConfig{KeepDaily: 1},
+			Postgres:  &config.PostgresConfig{Globals: true},
+		}},
+	}
+
+	var results []BackupResult
+	for i := 0; i < 2; i++ {
+		res, err := RunBackupWithResults(context.Background(), cfg, false)
+		if err != nil {
+			t.Fatalf("backup: %v", err)
+		}
+		results = res
+	}
+	res := results[0]
+	if !strings.HasPrefix(res.GlobalsKey, "app/globals/") || !strings.HasSuffix(res.GlobalsKey, ".sql.gz.enc") {
+		t.Fatalf("unexpected globals key %q", res.GlobalsKey)
+	}
+	if got := readFile(t, filepath.Join(work, "dumpall.args")); got != "--globals-only --host db --port 5432 --database app --username postgres\n" {
+		t.Fatalf("unexpected pg_dumpall args %q", got)
+	}
+	// Retention prunes dumps and globals separately, keeping one of each.
+	for _, dir := range []string{"app", "app/globals"} {
+		entries, err := os.ReadDir(filepath.Join(store, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := 0
+		for _, e := range entries {
+			if !e.IsDir() {
+				files++
+			}
+		}
+		if files != 1 {
+			t.Fatalf("expected 1 backup in %s after retention, got %d", dir, files)
+		}
+	}
+
+	globalsPath := filepath.Join(store, filepath.FromSlash(res.GlobalsKey))
+	opts := RestoreOptions{DBName: "app", FromPath: res.Dest, Globals: globalsPath, StrictSniff: true, DryRun: true}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "order")); err == nil {
+		t.Fatal("dry run must not run psql or pg_restore")
+	}
+
+	opts.DryRun = false
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "order")); got != "psql\npg_restore\n" {
+		t.Fatalf("expected globals applied before pg_restore, got %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "globals.sql")); !strings.Contains(got, "CREATE ROLE app;") {
+		t.Fatalf("unexpected globals script %q", got)
+	}
+
+	// The database dump is not SQL text, so it cannot be passed as globals.
+	opts.Globals = res.Dest
+	if err := RunRestore(context.Background(), cfg, opts); err == nil || !strings.Contains(err.Error(), "expected pg_dumpall SQL text") {
+		t.Fatalf("expected globals kind error, got %v", err)
+	}
+}
+
+func TestRestoreGlobalsRejectedForOtherEngines(t *testing.T) {
+	cfg := mysqlTestConfig(t.TempDir())
+	err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "shop", FromPath: "x", Globals: "y"})
+	if err == nil || !strings.Contains(err.Error(), "--globals not supported for mysql") {
+		t.Fatalf("expected --globals rejection, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+	"github.com/dev-tams/backupkit/internal/notify"
+)
+
+// hookRun describes the backup or restore a hook runs for. It reaches the hook
+// as BACKUPKIT_* environment variables; empty fields are left out.
+type hookRun struct {
+	Operation string // "backup" or "restore"
+	Key       string
+	Dest      string
+	From      string
+	Bytes     int64
+	Status    string
+	Err       error
+}
+
+func (r hookRun) env(db config.DatabaseConfig, event string) []string {
+	env := []string{
+		"BACKUPKIT_HOOK=" + event,
+		"BACKUPKIT_OPERATION=" + r.Operation,
+		"BACKUPKIT_DB=" + db.Name,
+		"BACKUPKIT_DB_TYPE=" + db.Type,
+	}
+	for _, kv := range []struct{ key, value string }{
+		{"BACKUPKIT_KEY", r.Key},
+		{"BACKUPKIT_DEST", r.Dest},
+		{"BACKUPKIT_FROM", r.From},
+		{"BACKUPKIT_STATUS", r.Status},
+	} {
+		if kv.value != "" {
+			env = append(env, kv.key+"="+kv.value)
+		}
+	}
+	if r.Status != "" {
+		env = append(env, "BACKUPKIT_BYTES="+strconv.FormatInt(r.Bytes, 10))
+	}
+	if r.Err != nil {
+		env = append(env, "BACKUPKIT_ERROR="+strings.TrimSpace(r.Err.Error()))
+	}
+	return env
+}
+
+// runHook runs one configured hook; a nil hook is a no-op. The hook's output
+// goes to stderr so it never mixes with a backup written to stdout. A failing
+// hook returns an error only with on_error: abort; otherwise the failure is
+// logged to logw as a warning. on_failure hooks always warn.
+func runHook(ctx context.Context, db config.DatabaseConfig, event string, hook *config.HookConfig, run hookRun, logw io.Writer) error {
+	if hook == nil {
+		return nil
+	}
+	timeout, err := hook.TimeoutDuration()
+	if err != nil {
+		return fmt.Errorf("hooks.%s: %w", event, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Env = append(os.Environ(), run.env(db, event)...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if hook.OnError == "warn" || event == "on_failure" {
+		fmt.Fprintf(logw, "warning: hooks.%s failed: db=%s: %v\n", event, db.Name, err)
+		return nil
+	}
+	return fmt.Errorf("hooks.%s: %w", event, err)
+}
+
+// hooksOf returns the hooks of db, or an empty set.
+func hooksOf(db config.DatabaseConfig) config.HooksConfig {
+	if db.Hooks == nil {
+		return config.HooksConfig{}
+	}
+	return *db.Hooks
+}
+
+// finishBackupHooks runs post_backup when the backup got past pre_backup, then
+// on_failure when res failed. A post_backup hook that aborts turns a successful
+// res into a failure; the backup itself stays in storage. The hooks run even
+// when ctx was canceled, like notifications.
+func finishBackupHooks(ctx context.Context, db config.DatabaseConfig, res *BackupResult, preDone bool, logw io.Writer) {
+	hooks := hooksOf(db)
+	ctx = context.WithoutCancel(ctx)
+	run := func() hookRun {
+		return hookRun{Operation: "backup", Key: res.Key, Dest: res.Dest, Bytes: res.Bytes, Status: res.Status, Err: res.Err}
+	}
+	if preDone {
+		if err := runHook(ctx, db, "post_backup", hooks.PostBackup, run(), logw); err != nil && res.Err == nil {
+			res.Status = notify.StatusFailure
+			res.Err = fmt.Errorf("backup failed for %s: %w", db.Name, err)
+		}
+	}
+	if res.Err != nil {
+		_ = runHook(ctx, db, "on_failure", hooks.OnFailure, run(), logw)
+	}
+}
+
+// finishRestoreHooks is finishBackupHooks for restores: it runs post_restore
+// when the restore got past pre_restore, then on_failure when the restore or
+// post_restore failed, and returns the restore's outcome.
+func finishRestoreHooks(ctx context.Context, db config.DatabaseConfig, run hookRun, preDone bool, err error, logw io.Writer) error {
+	hooks := hooksOf(db)
+	ctx = context.WithoutCancel(ctx)
+	run.Status, run.Err = notify.StatusSuccess, err
+	if err != nil {
+		run.Status = notify.StatusFailure
+	}
+	if preDone {
+		if hookErr := runHook(ctx, db, "post_restore", hooks.PostRestore, run, logw); hookErr != nil && err == nil {
+			err = fmt.Errorf("restore failed for %s: %w", db.Name, hookErr)
+			run.Status, run.Err = notify.StatusFailure, err
+		}
+	}
+	if err != nil {
+		_ = runHook(ctx, db, "on_failure", hooks.OnFailure, run, logw)
+	}
+	return err
+}
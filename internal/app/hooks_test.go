@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+	"github.com/dev-tams/backupkit/internal/notify"
+)
+
+// hooksTestConfig is a command database whose hooks all run the fake "hook"
+// tool, which logs one line per call to $WORK/hooks.log.
+func hooksTestConfig(t *testing.T, backupScript string) (*config.Config, string) {
+	t.Helper()
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	t.Setenv("WORK", work)
+	fakeTool(t, bin, "snapshot-tool", backupScript)
+	fakeTool(t, bin, "load-tool", `cat > "$WORK/restored"
+`)
+	fakeTool(t, bin, "hook", `echo "$1 $BACKUPKIT_OPERATION db=$BACKUPKIT_DB status=$BACKUPKIT_STATUS bytes=$BACKUPKIT_BYTES key=$BACKUPKIT_KEY from=$BACKUPKIT_FROM err=$BACKUPKIT_ERROR" >> "$WORK/hooks.log"
+[ "$1" != "$FAIL_HOOK" ]
+`)
+	hook := func(name string) *config.HookConfig {
+		return &config.HookConfig{Command: []string{"hook", name}}
+	}
+	cfg := commandTestConfig(t.TempDir(), &config.CommandConfig{
+		Backup:  []string{"snapshot-tool"},
+		Restore: []string{"load-tool"},
+	})
+	cfg.Databases[0].Backup.Compression = false
+	cfg.Databases[0].Hooks = &config.HooksConfig{
+		PreBackup:   hook("pre_backup"),
+		PostBackup:  hook("post_backup"),
+		OnFailure:   hook("on_failure"),
+		PreRestore:  hook("pre_restore"),
+		PostRestore: hook("post_restore"),
+	}
+	return cfg, work
+}
+
+func hookLog(t *testing.T, work string) []string {
+	t.Helper()
+	return strings.Split(strings.TrimSpace(readFile(t, filepath.Join(work, "hooks.log"))), "\n")
+}
+
+func TestBackupHooksRunAroundBackup(t *testing.T) {
+	cfg, work := hooksTestConfig(t, `printf snapshot
+`)
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	want := []string{
+		"pre_backup backup db=etcd status= bytes= key= from= err=",
+		"post_backup backup db=etcd status=success bytes=8 key=" + results[0].Key + " from= err=",
+	}
+	if got := hookLog(t, work); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected hook calls:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestBackupHooksOnBackupFailure(t *testing.T) {
+	cfg, work := hooksTestConfig(t, `echo "cluster unreachable" >&2
+exit 3
+`)
+	if _, err := RunBackupWithResults(context.Background(), cfg, false); err == nil {
+		t.Fatalf("expected backup failure")
+	}
+	got := hookLog(t, work)
+	if len(got) != 3 || !strings.HasPrefix(got[0], "pre_backup ") {
+		t.Fatalf("expected pre_backup, post_backup and on_failure, got:\n%s", strings.Join(got, "\n"))
+	}
+	for _, line := range got[1:] {
+		if !strings.Contains(line, "status=failure") || !strings.Contains(line, "cluster unreachable") {
+			t.Fatalf("expected failure status and error, got %q", line)
+		}
+	}
+	if !strings.HasPrefix(got[1], "post_backup ") || !strings.HasPrefix(got[2], "on_failure ") {
+		t.Fatalf("expected post_backup before on_failure, got:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestPreBackupHookAbortsOrWarns(t *testing.T) {
+	cfg, work := hooksTestConfig(t, `echo ran > "$WORK/dumped"
+printf snapshot
+`)
+	t.Setenv("FAIL_HOOK", "pre_backup")
+
+	_, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "hooks.pre_backup") {
+		t.Fatalf("expected pre_backup abort, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "dumped")); err == nil {
+		t.Fatalf("an aborted pre_backup must stop the backup")
+	}
+	got := hookLog(t, work)
+	if len(got) != 2 || !strings.HasPrefix(got[1], "on_failure ") {
+		t.Fatalf("expected on_failure but no post_backup after an aborted pre_backup, got:\n%s", strings.Join(got, "\n"))
+	}
+
+	cfg.Databases[0].Hooks.PreBackup.OnError = "warn"
+	if _, err := RunBackupWithResults(context.Background(), cfg, false); err != nil {
+		t.Fatalf("pre_backup with on_error warn must not fail the backup: %v", err)
+	}
+}
+
+func TestPostBackupHookAbortFailsResult(t *testing.T) {
+	cfg, _ := hooksTestConfig(t, `printf snapshot
+`)
+	t.Setenv("FAIL_HOOK", "post_backup")
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "hooks.post_backup") {
+		t.Fatalf("expected post_backup failure, got %v", err)
+	}
+	if results[0].Status != notify.StatusFailure || results[0].Key == "" {
+		t.Fatalf("expected a failed result that still records the stored key, got %+v", results[0])
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	cfg, _ := hooksTestConfig(t, `printf snapshot
+`)
+	cfg.Databases[0].Hooks.PreBackup = &config.HookConfig{Command: []string{"sleep", "5"}, Timeout: "100ms"}
+	_, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("expected hook timeout, got %v", err)
+	}
+}
+
+func TestRestoreHooks(t *testing.T) {
+	cfg, work := hooksTestConfig(t, `printf snapshot
+`)
+	cfg.Databases[0].Hooks.PreBackup, cfg.Databases[0].Hooks.PostBackup = nil, nil
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	from := results[0].Dest
+
+	if err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "etcd", FromPath: from}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	want := []string{
+		"pre_restore restore db=etcd status= bytes= key= from=" + from + " err=",
+		"post_restore restore db=etcd status=success bytes=8 key= from=" + from + " err=",
+	}
+	if got := hookLog(t, work); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected hook calls:\n%s", strings.Join(got, "\n"))
+	}
+
+	t.Setenv("FAIL_HOOK", "pre_restore")
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "etcd", FromPath: from})
+	if err == nil || !strings.Contains(err.Error(), "hooks.pre_restore") {
+		t.Fatalf("expected pre_restore abort, got %v", err)
+	}
+	if got := hookLog(t, work); !strings.HasPrefix(got[len(got)-1], "on_failure restore ") {
+		t.Fatalf("expected on_failure after the aborted restore, got:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestRestoreHooksRunForFileRestores(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	fakeTool(t, bin, "redis-cli", `printf 'REDIS0011rdb-body'
+`)
+	// The hooks stand in for stopping and starting redis around the RDB swap.
+	fakeTool(t, bin, "hook", `if [ -e "$BACKUPKIT_DEST" ]; then state=present; else state=missing; fi
+echo "$1 dest=$state" >> `+work+`/hooks.log
+`)
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "queues",
+			Type:       "redis",
+			Connection: config.ConnectionConfig{Host: "cache", Port: 6379},
+			Backup:     config.BackupConfig{Storage: "local"},
+			Hooks: &config.HooksConfig{
+				PreRestore:  &config.HookConfig{Command: []string{"hook", "pre_restore"}},
+				PostRestore: &config.HookConfig{Command: []string{"hook", "post_restore"}},
+			},
+		}},
+	}
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "dump.rdb")
+	if err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "queues", FromPath: results[0].Dest, OutPath: out}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := hookLog(t, work); strings.Join(got, "\n") != "pre_restore dest=missing\npost_restore dest=present" {
+		t.Fatalf("expected hooks around the file restore, got:\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// fakeProbePsql answers the host probe per --host from $WORK/<host>, which holds
+// "t|<lag>" for a standby or "f|0" for a primary; a missing file is a down host.
+const fakeProbePsql = `while [ $# -gt 0 ]; do case "$1" in --host) host="$2"; shift ;; esac; shift; done
+if [ ! -f "$WORK/$host" ]; then echo "psql: error: connection to server at \"$host\" failed: Connection refused" >&2; exit 2; fi
+cat "$WORK/$host"
+`
+
+func hostsTestConfig(store string) *config.Config {
+	return &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name: "app",
+			Type: "postgres",
+			Connection: config.ConnectionConfig{
+				Hosts:        []string{"primary", "replica1:6432", "replica2"},
+				Port:         5432,
+				Database:     "app",
+				User:         "backup",
+				Prefer:       "standby",
+				MaxReplayLag: "1m",
+			},
+			Backup: config.BackupConfig{Storage: "local"},
+		}},
+	}
+}
+
+func TestBackupPrefersHealthyStandby(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	t.Setenv("WORK", work)
+	fakeTool(t, bin, "psql", fakeProbePsql)
+	fakeTool(t, bin, "pg_dump", `echo "$@" > "$WORK/dump.args"
+printf 'PGDMP-archive'
+`)
+	for host, state := range map[string]string{"primary": "f|0", "replica1": "t|300.5", "replica2": "t|2.25"} {
+		if err := os.WriteFile(filepath.Join(work, host), []byte(state+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := hostsTestConfig(t.TempDir())
+	res, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if res[0].Host != "replica2:5432" {
+		t.Fatalf("expected the lagging replica1 to be skipped for replica2, got %q", res[0].Host)
+	}
+	if got := readFile(t, filepath.Join(work, "dump.args")); !strings.HasPrefix(got, "--host replica2 --port 5432 ") {
+		t.Fatalf("unexpected pg_dump args %q", got)
+	}
+
+	// Without a usable standby the backup falls back to the primary.
+	if err := os.Remove(filepath.Join(work, "replica2")); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = RunBackupWithResults(context.Background(), cfg, false); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if res[0].Host != "primary:5432" {
+		t.Fatalf("expected fallback to the primary, got %q", res[0].Host)
+	}
+
+	if err := os.Remove(filepath.Join(work, "primary")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = RunBackupWithResults(context.Background(), cfg, false)
+	for _, want := range []string{"no usable postgres host", "primary:5432: probe failed", "replica1:6432: standby replay lag 5m0.5s exceeds max_replay_lag 1m0s"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in error, got %v", want, err)
+		}
+	}
+}
+
+func TestRestoreUsesPrimaryHost(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	t.Setenv("WORK", work)
+	fakeTool(t, bin, "psql", fakeProbePsql)
+	fakeTool(t, bin, "pg_restore", `case "$*" in *--list*) exit 0 ;; esac
+echo "$@" > "$WORK/restore.args"
+cat > /dev/null
+`)
+	for host, state := range map[string]string{"primary": "f|0", "replica2": "t|0"} {
+		if err := os.WriteFile(filepath.Join(work, host), []byte(state+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := hostsTestConfig(t.TempDir())
+	cfg.Databases[0].Connection.Hosts = []string{"replica2", "primary"}
+	from := filepath.Join(t.TempDir(), "app.dump")
+	if err := os.WriteFile(from, []byte("PGDMP-archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "app", FromPath: from}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.args")); !strings.HasPrefix(got, "--host primary --port 5432 ") {
+		t.Fatalf("expected restore into the primary, got %q", got)
+	}
+}
+
+func TestRestoreDryRunSkipsHostProbe(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	t.Setenv("WORK", work)
+	fakeTool(t, bin, "psql", `echo "$@" >> "$WORK/psql.calls"
+exit 2
+`)
+	fakeTool(t, bin, "pg_restore", `exit 0
+`)
+	cfg := hostsTestConfig(t.TempDir())
+	from := filepath.Join(t.TempDir(), "app.dump")
+	if err := os.WriteFile(from, []byte("PGDMP-archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := RestoreOptions{DBName: "app", FromPath: from, DryRun: true}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "psql.calls")); err == nil {
+		t.Fatalf("dry run must not probe connection.hosts: %s", readFile(t, filepath.Join(work, "psql.calls")))
+	}
+}
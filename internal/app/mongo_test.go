@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestMongoBackupAndRestoreWithFakeBinaries(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	// A mongodump archive starts with the little-endian magic 0x8199e26d.
+	fakeTool(t, bin, "mongodump", `echo "$@" > `+work+`/dump.args
+printf '\155\342\231\201archive-body'
+`)
+	fakeTool(t, bin, "mongorestore", `echo "$@" > `+work+`/restore.args
+cat > `+work+`/restore.archive
+`)
+
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "events",
+			Type:       "mongo",
+			Connection: config.ConnectionConfig{Host: "mongo", Port: 27017, Database: "events"},
+			Backup:     config.BackupConfig{Storage: "local", Compression: true},
+		}},
+	}
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".archive.gz") {
+		t.Fatalf("expected .archive.gz key, got %s", results[0].Key)
+	}
+	if got := readFile(t, filepath.Join(work, "dump.args")); got != "--archive --host=mongo --port=27017 --db=events\n" {
+		t.Fatalf("unexpected mongodump args %q", got)
+	}
+
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "events", FromPath: results[0].Dest, Clean: true, StrictSniff: true})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.args")); got != "--archive --host=mongo --port=27017 --nsInclude=events.* --drop\n" {
+		t.Fatalf("unexpected mongorestore args %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.archive")); got != "\x6d\xe2\x99\x81archive-body" {
+		t.Fatalf("unexpected restored archive %q", got)
+	}
+}
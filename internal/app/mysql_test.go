@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// fakeTool installs an executable shell script named name on PATH.
+func fakeTool(t *testing.T, binDir, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	if err := os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func withFakeBin(t *testing.T) string {
+	t.Helper()
+	bin := t.TempDir()
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return bin
+}
+
+func mysqlTestConfig(store string) *config.Config {
+	triggers := false
+	return &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name: "shop",
+			Type: "mysql",
+			Connection: config.ConnectionConfig{
+				Host: "db", Port: 3306, Database: "shop", User: "app", Password: "pw",
+			},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+			MySQL: &config.MySQLConfig{Routines: true, Triggers: &triggers},
+		}},
+	}
+}
+
+func TestMySQLBackupAndRestoreWithFakeBinaries(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	fakeTool(t, bin, "mysqldump", `echo "$@" > `+work+`/dump.args
+echo "$MYSQL_PWD" > `+work+`/dump.pwd
+printf -- '-- MySQL dump 10.13\nCREATE TABLE t (id int);\nINSERT INTO t VALUES (1);\n'
+`)
+	fakeTool(t, bin, "mysql", `echo "$@" > `+work+`/restore.args
+echo "$MYSQL_PWD" > `+work+`/restore.pwd
+cat > `+work+`/restore.sql
+`)
+
+	store := t.TempDir()
+	cfg := mysqlTestConfig(store)
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".sql.gz.enc") {
+		t.Fatalf("expected .sql.gz.enc key, got %s", results[0].Key)
+	}
+	if _, ok := parseBackupTimeFromKey(results[0].Key); !ok {
+		t.Fatalf("retention cannot parse key %s", results[0].Key)
+	}
+
+	dumpArgs := readFile(t, filepath.Join(work, "dump.args"))
+	for _, want := range []string{"--single-transaction", "--routines", "--skip-triggers", "--user app", "shop"} {
+		if !strings.Contains(dumpArgs, want) {
+			t.Fatalf("expected %q in mysqldump args %q", want, dumpArgs)
+		}
+	}
+	if strings.Contains(dumpArgs, "pw") || readFile(t, filepath.Join(work, "dump.pwd")) != "pw\n" {
+		t.Fatal("expected password via MYSQL_PWD only")
+	}
+
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "shop", FromPath: results[0].Dest})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.sql")); !strings.Contains(got, "INSERT INTO t VALUES (1);") {
+		t.Fatalf("unexpected restored script %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.args")); got != "--host db --port 3306 --user app shop\n" {
+		t.Fatalf("unexpected mysql args %q", got)
+	}
+}
+
+func readFile(t *testing.T, p string) string {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
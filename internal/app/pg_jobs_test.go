@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// fakeDirDump is a pg_dump that writes a directory-format dump to --file.
+const fakeDirDump = `for a in "$@"; do case "$a" in --file=*) dir="${a#--file=}" ;; esac; done
+echo "$@" > "$WORK/dump.args"
+mkdir -p "$dir"
+printf 'PGDMP\001\016\000\004\010\005' > "$dir/toc.dat"
+printf 'table data' > "$dir/3001.dat.gz"
+`
+
+func jobsTestConfig(store string) *config.Config {
+	return &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "app",
+			Type:       "postgres",
+			Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "postgres"},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+			Postgres: &config.PostgresConfig{Jobs: 4},
+		}},
+	}
+}
+
+func TestPostgresParallelDirectoryDumpRoundTrip(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	tmp := t.TempDir()
+	t.Setenv("WORK", work)
+	t.Setenv("TMPDIR", tmp)
+	fakeTool(t, bin, "pg_dump", fakeDirDump)
+	fakeTool(t, bin, "pg_restore", `case "$*" in *--list*) exit 0 ;; esac
+echo "$@" > "$WORK/restore.args"
+for a in "$@"; do dir="$a"; done
+cat "$dir/3001.dat.gz" > "$WORK/restored"
+`)
+
+	cfg := jobsTestConfig(t.TempDir())
+	res, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(res[0].Dest, ".tar.gz.enc") {
+		t.Fatalf("expected a .tar.gz.enc backup, got %s", res[0].Dest)
+	}
+	if got := readFile(t, filepath.Join(work, "dump.args")); !strings.Contains(got, "--format=directory --jobs=4 --file="+tmp) {
+		t.Fatalf("unexpected pg_dump args %q", got)
+	}
+	assertEmptyDir(t, tmp)
+
+	opts := RestoreOptions{DBName: "app", FromPath: res[0].Dest, Jobs: 4, StrictSniff: true, TempDir: t.TempDir()}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(work, "restored")); got != "table data" {
+		t.Fatalf("unexpected restored data %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "restore.args")); !strings.Contains(got, "--jobs 4") {
+		t.Fatalf("expected a parallel pg_restore, got %q", got)
+	}
+}
+
+func TestPostgresParallelDumpFailureRemovesTempDir(t *testing.T) {
+	bin := withFakeBin(t)
+	tmp := t.TempDir()
+	t.Setenv("WORK", t.TempDir())
+	t.Setenv("TMPDIR", tmp)
+	fakeTool(t, bin, "pg_dump", fakeDirDump+`echo 'pg_dump: error: connection lost' >&2
+exit 1
+`)
+
+	_, err := RunBackupWithResults(context.Background(), jobsTestConfig(t.TempDir()), false)
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("expected pg_dump failure, got %v", err)
+	}
+	assertEmptyDir(t, tmp)
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected %s to be empty, found %s", dir, entries[0].Name())
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestBackupChecksPgDumpAgainstServerVersion(t *testing.T) {
+	withFakeBin(t) // nothing in PATH; the tools come from pg_bin_dir
+	old, current := t.TempDir(), t.TempDir()
+	for dir, version := range map[string]string{old: "14.11", current: "16.4"} {
+		fakeTool(t, dir, "pg_dump", `case "$1" in --version) echo "pg_dump (PostgreSQL) `+version+`"; exit 0 ;; esac
+printf 'PGDMP-archive'
+`)
+		fakeTool(t, dir, "psql", `echo '16.2 (Debian 16.2-1.pgdg120+2)'
+`)
+	}
+
+	store := t.TempDir()
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "app",
+			Type:       "postgres",
+			Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "postgres"},
+			Backup:     config.BackupConfig{Storage: "local"},
+			Postgres:   &config.PostgresConfig{PgBinDir: old},
+		}},
+	}
+
+	res, err := RunBackupWithResults(context.Background(), cfg, false)
+	want := "pg_dump 14.11 is older than the PostgreSQL 16.2 server and cannot back it up; install the PostgreSQL 16 client tools"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	if res[0].ClientVersion != "14.11" || res[0].ServerVersion != "16.2" || res[0].Key != "" {
+		t.Fatalf("expected a failed result without a backup, got %+v", res[0])
+	}
+	if entries, _ := os.ReadDir(filepath.Join(store, "app")); len(entries) != 0 {
+		t.Fatalf("expected no backup to be written, found %d files", len(entries))
+	}
+
+	cfg.Databases[0].Postgres.PgBinDir = current
+	if res, err = RunBackupWithResults(context.Background(), cfg, false); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if res[0].ClientVersion != "16.4" || res[0].ServerVersion != "16.2" {
+		t.Fatalf("expected versions in the result, got client=%q server=%q", res[0].ClientVersion, res[0].ServerVersion)
+	}
+}
+
+func TestFailedBackupKeepsVersions(t *testing.T) {
+	bin := withFakeBin(t)
+	fakeTool(t, bin, "pg_dump", `case "$1" in --version) echo "pg_dump (PostgreSQL) 16.4"; exit 0 ;; esac
+echo "permission denied for table secrets" >&2
+exit 1
+`)
+	fakeTool(t, bin, "psql", `echo '16.2'
+`)
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "app",
+			Type:       "postgres",
+			Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "postgres"},
+			Backup:     config.BackupConfig{Storage: "local"},
+		}},
+	}
+
+	res, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected pg_dump failure, got %v", err)
+	}
+	if res[0].ClientVersion != "16.4" || res[0].ServerVersion != "16.2" {
+		t.Fatalf("expected versions in the failed result, got client=%q server=%q", res[0].ClientVersion, res[0].ServerVersion)
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func physicalTestConfig(store string) *config.Config {
+	return &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: store}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "cluster",
+			Type:       "postgres",
+			Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "postgres", User: "replicator", Password: "pw"},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+			Postgres: &config.PostgresConfig{Mode: "physical"},
+		}},
+	}
+}
+
+func TestPostgresPhysicalBackupAndRestore(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	base := tarBytes(t, map[string][]byte{
+		"backup_label":         []byte("START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n"),
+		"PG_VERSION":           []byte("16\n"),
+		"postgresql.auto.conf": []byte("# Do not edit this file manually!\n"),
+		"base/1/1259":          []byte("heap"),
+	}, "backup_label", "PG_VERSION", "postgresql.auto.conf", "base/", "base/1/", "base/1/1259")
+	if err := os.WriteFile(filepath.Join(work, "base.tar"), base, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fakeTool(t, bin, "pg_basebackup", `echo "$@" > `+work+`/basebackup.args
+echo "$PGPASSWORD" > `+work+`/basebackup.pw
+cat `+work+`/base.tar
+`)
+
+	cfg := physicalTestConfig(t.TempDir())
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".tar.gz.enc") {
+		t.Fatalf("expected .tar.gz.enc key, got %s", results[0].Key)
+	}
+	if got := readFile(t, filepath.Join(work, "basebackup.args")); got != "--host db --port 5432 --username replicator --format=tar --pgdata=- --wal-method=fetch\n" {
+		t.Fatalf("unexpected pg_basebackup args %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "basebackup.pw")); got != "pw\n" {
+		t.Fatalf("expected password via PGPASSWORD, got %q", got)
+	}
+
+	datadir := filepath.Join(t.TempDir(), "pgdata")
+	opts := RestoreOptions{DBName: "cluster", FromPath: results[0].Dest, OutPath: datadir, StrictSniff: true, DryRun: true}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(datadir); err == nil {
+		t.Fatal("dry run must not create the data directory")
+	}
+
+	opts.DryRun = false
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(datadir, "base", "1", "1259")); got != "heap" {
+		t.Fatalf("unexpected restored relation file %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(datadir, "recovery.signal")); err != nil {
+		t.Fatalf("expected recovery.signal: %v", err)
+	}
+	auto := readFile(t, filepath.Join(datadir, "postgresql.auto.conf"))
+	if !strings.HasPrefix(auto, "# Do not edit") || !strings.Contains(auto, "recovery_target = 'immediate'") {
+		t.Fatalf("unexpected postgresql.auto.conf:\n%s", auto)
+	}
+	if fi, _ := os.Stat(datadir); fi.Mode().Perm() != 0o700 {
+		t.Fatalf("expected data directory mode 0700, got %v", fi.Mode().Perm())
+	}
+
+	if err := RunRestore(context.Background(), cfg, opts); err == nil || !strings.Contains(err.Error(), "is not empty") {
+		t.Fatalf("expected refusal to restore into a non-empty data directory, got %v", err)
+	}
+
+	// A logical postgres database gets a hint instead of a pg_restore failure.
+	cfg.Databases[0].Postgres = nil
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "cluster", FromPath: results[0].Dest})
+	if err == nil || !strings.Contains(err.Error(), "postgres.mode: physical") {
+		t.Fatalf("expected physical mode hint, got %v", err)
+	}
+}
+
+func TestPostgresPhysicalRestoreRejectsOtherTars(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "site.tar")
+	site := tarBytes(t, map[string][]byte{"srv/site/index.html": []byte("<html>")}, "srv/", "srv/site/", "srv/site/index.html")
+	if err := os.WriteFile(from, site, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	datadir := filepath.Join(t.TempDir(), "pgdata")
+	err := RunRestore(context.Background(), physicalTestConfig(t.TempDir()), RestoreOptions{DBName: "cluster", FromPath: from, OutPath: datadir})
+	if err == nil || !strings.Contains(err.Error(), "not a pg_basebackup tar (got dirtar)") {
+		t.Fatalf("expected a non-basebackup tar to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(datadir); err == nil {
+		t.Fatal("a rejected tar must not be extracted into the data directory")
+	}
+}
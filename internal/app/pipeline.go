@@ -3,25 +3,14 @@ package app
 import (
 	"io"
 
+	"github.com/dev-tams/backupkit/internal/backup"
 	"github.com/dev-tams/backupkit/internal/compression"
 	"github.com/dev-tams/backupkit/internal/encryption"
 )
 
-type closeStack []io.Closer
-
-func (cs *closeStack) add(c io.Closer) {
-	*cs = append(*cs, c)
-}
-
-func (cs closeStack) closeAll() {
-	for i := len(cs) - 1; i >= 0; i-- {
-		_ = cs[i].Close()
-	}
-}
-
-func gzipReader(src io.Reader, closers *closeStack) io.Reader {
+func gzipReader(src io.Reader, closers *backup.CloseStack) io.Reader {
 	pr, pw := io.Pipe()
-	closers.add(pr)
+	closers.Add(pr)
 
 	go func() {
 		_, err := compression.Gzip(pw, src)
@@ -35,9 +24,9 @@ func gzipReader(src io.Reader, closers *closeStack) io.Reader {
 	return pr
 }
 
-func encryptReader(src io.Reader, password string, closers *closeStack) io.Reader {
+func encryptReader(src io.Reader, password string, closers *backup.CloseStack) io.Reader {
 	pr, pw := io.Pipe()
-	closers.add(pr)
+	closers.Add(pr)
 
 	go func() {
 		_, err := encryption.EncryptAESGCM(pw, src, password)
@@ -50,9 +39,9 @@ func encryptReader(src io.Reader, password string, closers *closeStack) io.Reade
 	return pr
 }
 
-func gunzipReader(src io.Reader, closers *closeStack) io.Reader {
+func gunzipReader(src io.Reader, closers *backup.CloseStack) io.Reader {
 	pr, pw := io.Pipe()
-	closers.add(pr)
+	closers.Add(pr)
 
 	go func() {
 		_, err := compression.Gunzip(pw, src)
@@ -66,9 +55,9 @@ func gunzipReader(src io.Reader, closers *closeStack) io.Reader {
 	return pr
 }
 
-func decryptReader(src io.Reader, password string, closers *closeStack) io.Reader {
+func decryptReader(src io.Reader, password string, closers *backup.CloseStack) io.Reader {
 	pr, pw := io.Pipe()
-	closers.add(pr)
+	closers.Add(pr)
 
 	go func() {
 		_, err := encryption.DecryptAESGCM(pw, src, password)
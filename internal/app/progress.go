@@ -0,0 +1,206 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultProgressInterval = 5 * time.Second
+
+// countingReader counts bytes read from the stored backup object.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) Count() int64 { return c.n.Load() }
+
+// progressReporter periodically prints how much of the stored object has been
+// consumed, throughput, ETA and the current TOC entry.
+type progressReporter struct {
+	db       string
+	total    int64
+	src      *countingReader
+	jsonOut  bool
+	interval time.Duration
+	out      io.Writer
+	started  time.Time
+
+	mu    sync.Mutex
+	phase string
+	entry string
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+type progressEvent struct {
+	DB             string  `json:"db"`
+	Phase          string  `json:"phase"`
+	BytesRead      int64   `json:"bytes_read"`
+	TotalBytes     int64   `json:"total_bytes,omitempty"`
+	Percent        float64 `json:"percent,omitempty"`
+	BytesPerSecond float64 `json:"bytes_per_sec"`
+	ETASeconds     float64 `json:"eta_seconds,omitempty"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Entry          string  `json:"entry,omitempty"`
+	Final          bool    `json:"final,omitempty"`
+}
+
+// newProgressReporter returns nil when format is empty so callers can use the
+// nil-safe methods unconditionally. total <= 0 means the size is unknown.
+func newProgressReporter(db string, total int64, src *countingReader, format string, interval time.Duration, out io.Writer) *progressReporter {
+	if format == "" {
+		return nil
+	}
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return &progressReporter{
+		db:       db,
+		total:    total,
+		src:      src,
+		jsonOut:  format == "json",
+		interval: interval,
+		out:      out,
+		phase:    "restoring",
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+func (p *progressReporter) start() {
+	if p == nil {
+		return
+	}
+	p.started = time.Now()
+	go func() {
+		defer close(p.doneCh)
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case now := <-t.C:
+				p.report(now, false)
+			}
+		}
+	}()
+}
+
+// stop ends periodic reporting and prints a final line.
+func (p *progressReporter) stop() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		<-p.doneCh
+		p.report(time.Now(), true)
+	})
+}
+
+func (p *progressReporter) SetPhase(phase string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.phase = phase
+	p.mu.Unlock()
+}
+
+func (p *progressReporter) SetEntry(entry string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.entry = entry
+	p.mu.Unlock()
+}
+
+func (p *progressReporter) snapshot(now time.Time, final bool) progressEvent {
+	p.mu.Lock()
+	phase, entry := p.phase, p.entry
+	p.mu.Unlock()
+
+	read := p.src.Count()
+	elapsed := now.Sub(p.started).Seconds()
+	ev := progressEvent{
+		DB:             p.db,
+		Phase:          phase,
+		BytesRead:      read,
+		ElapsedSeconds: elapsed,
+		Entry:          entry,
+		Final:          final,
+	}
+	if elapsed > 0 {
+		ev.BytesPerSecond = float64(read) / elapsed
+	}
+	if p.total > 0 {
+		ev.TotalBytes = p.total
+		ev.Percent = 100 * float64(read) / float64(p.total)
+		if ev.BytesPerSecond > 0 && read < p.total {
+			ev.ETASeconds = float64(p.total-read) / ev.BytesPerSecond
+		}
+	}
+	return ev
+}
+
+func (p *progressReporter) report(now time.Time, final bool) {
+	ev := p.snapshot(now, final)
+	if p.jsonOut {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(p.out, string(b))
+		return
+	}
+	fmt.Fprintln(p.out, formatProgress(ev))
+}
+
+func formatProgress(ev progressEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "restore progress: db=%s phase=%s read=%s", ev.DB, ev.Phase, humanBytes(ev.BytesRead))
+	if ev.TotalBytes > 0 {
+		fmt.Fprintf(&b, "/%s (%.1f%%)", humanBytes(ev.TotalBytes), ev.Percent)
+	}
+	fmt.Fprintf(&b, " rate=%s/s", humanBytes(int64(ev.BytesPerSecond)))
+	if ev.ETASeconds > 0 {
+		fmt.Fprintf(&b, " eta=%s", (time.Duration(ev.ETASeconds) * time.Second).String())
+	}
+	fmt.Fprintf(&b, " elapsed=%s", (time.Duration(ev.ElapsedSeconds) * time.Second).String())
+	if ev.Entry != "" {
+		fmt.Fprintf(&b, " entry=%q", ev.Entry)
+	}
+	if ev.Final {
+		b.WriteString(" final")
+	}
+	return b.String()
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
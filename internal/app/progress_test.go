@@ -0,0 +1,65 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountingReaderCountsBytes(t *testing.T) {
+	c := &countingReader{r: strings.NewReader("hello world")}
+	if _, err := io.Copy(io.Discard, c); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if got := c.Count(); got != 11 {
+		t.Fatalf("expected 11 bytes, got %d", got)
+	}
+}
+
+func TestProgressSnapshotComputesRateAndETA(t *testing.T) {
+	src := &countingReader{r: strings.NewReader(strings.Repeat("x", 25))}
+	io.Copy(io.Discard, src)
+	p := newProgressReporter("app_db", 100, src, "json", time.Second, io.Discard)
+	p.started = time.Unix(0, 0)
+
+	ev := p.snapshot(time.Unix(5, 0), false)
+	if ev.Percent != 25 || ev.BytesPerSecond != 5 || ev.ETASeconds != 15 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	var out bytes.Buffer
+	p.out = &out
+	p.report(time.Unix(5, 0), true)
+	var decoded map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("progress line is not JSON: %v: %q", err, out.String())
+	}
+	if decoded["bytes_read"] != float64(25) || decoded["final"] != true {
+		t.Fatalf("unexpected JSON %v", decoded)
+	}
+}
+
+func TestFormatProgressText(t *testing.T) {
+	got := formatProgress(progressEvent{
+		DB: "app_db", Phase: "restoring", BytesRead: 2048, TotalBytes: 4096, Percent: 50,
+		BytesPerSecond: 1024, ETASeconds: 2, ElapsedSeconds: 2, Entry: "creating TABLE public.users",
+	})
+	want := `restore progress: db=app_db phase=restoring read=2.0 KiB/4.0 KiB (50.0%) rate=1.0 KiB/s eta=2s elapsed=2s entry="creating TABLE public.users"`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestNewProgressReporterDisabled(t *testing.T) {
+	p := newProgressReporter("app_db", 0, nil, "", 0, io.Discard)
+	if p != nil {
+		t.Fatal("expected nil reporter without a format")
+	}
+	p.start()
+	p.SetPhase("spooling")
+	p.SetEntry("creating TABLE public.users")
+	p.stop()
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestRedisBackupAndRestoreToFile(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	fakeTool(t, bin, "redis-cli", `echo "$@" > `+work+`/cli.args
+echo "$REDISCLI_AUTH" > `+work+`/cli.auth
+echo "sending REPLCONF capa eof" >&2
+printf 'REDIS0011rdb-body'
+`)
+
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:       "queues",
+			Type:       "redis",
+			Connection: config.ConnectionConfig{Host: "cache", Port: 6379, Password: "pw"},
+			Backup: config.BackupConfig{
+				Storage:    "local",
+				Encryption: config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+			// Restoring to a file never touches the server, so no confirmation is needed.
+			Protected: true,
+		}},
+	}
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".rdb.enc") {
+		t.Fatalf("expected .rdb.enc key, got %s", results[0].Key)
+	}
+	if got := readFile(t, filepath.Join(work, "cli.args")); got != "-h cache -p 6379 --rdb -\n" {
+		t.Fatalf("unexpected redis-cli args %q", got)
+	}
+	if got := readFile(t, filepath.Join(work, "cli.auth")); got != "pw\n" {
+		t.Fatalf("expected password via REDISCLI_AUTH, got %q", got)
+	}
+
+	out := filepath.Join(t.TempDir(), "dump.rdb")
+	opts := RestoreOptions{DBName: "queues", FromPath: results[0].Dest, OutPath: out, StrictSniff: true}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readFile(t, out); got != "REDIS0011rdb-body" {
+		t.Fatalf("unexpected rdb file %q", got)
+	}
+
+	if err := RunRestore(context.Background(), cfg, opts); err == nil || !strings.Contains(err.Error(), "--clean to overwrite") {
+		t.Fatalf("expected refusal to overwrite, got %v", err)
+	}
+	opts.Clean = true
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore --clean: %v", err)
+	}
+}
+
+func TestRestoreOutRejectedForServerEngines(t *testing.T) {
+	cfg := mysqlTestConfig(t.TempDir())
+	err := RunRestore(context.Background(), cfg, RestoreOptions{DBName: "shop", FromPath: "x", OutPath: "y"})
+	if err == nil || !strings.Contains(err.Error(), "--out not supported for mysql") {
+		t.Fatalf("expected --out rejection, got %v", err)
+	}
+}
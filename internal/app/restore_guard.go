@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal reports whether restore can prompt for confirmation; replaced in tests.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// guardProtectedRestore blocks writes to a protected database unless the operator
+// passed --i-understand or types the database name at an interactive prompt.
+func guardProtectedRestore(db *config.DatabaseConfig, opts RestoreOptions, target string, in io.Reader, out io.Writer) error {
+	if !db.Protected || opts.IUnderstand {
+		return nil
+	}
+	// With --from - stdin carries the backup, so there is nobody to prompt.
+	if !stdinIsTerminal() || opts.FromPath == "-" {
+		return fmt.Errorf("restore: db %s is protected; rerun with --i-understand to restore into %s", db.Name, target)
+	}
+
+	action := "restore into"
+	if opts.Clean {
+		action = "drop and recreate objects in"
+	}
+	fmt.Fprintf(out, "db %s is protected. This will %s %s.\nType the database name to continue: ", db.Name, action, target)
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && strings.TrimSpace(line) == "" {
+		return fmt.Errorf("restore: confirmation aborted for protected db %s", db.Name)
+	}
+	if strings.TrimSpace(line) != db.Name {
+		return fmt.Errorf("restore: confirmation did not match; protected db %s left untouched", db.Name)
+	}
+	return nil
+}
+
+// snapshotBeforeRestore backs up the restore target through the normal backup
+// pipeline and returns the result so the restore can be undone. Retention is
+// disabled for the snapshot so it can never prune the backup being restored;
+// the backup hooks and notifications run as for any other backup.
+func snapshotBeforeRestore(ctx context.Context, cfg *config.Config, db config.DatabaseConfig, verbose bool) (BackupResult, error) {
+	db.Retention = config.RetentionConfig{}
+
+	snapCfg := *cfg
+	snapCfg.Databases = []config.DatabaseConfig{db}
+
+	results, err := RunBackupWithResults(ctx, &snapCfg, verbose)
+	if err != nil {
+		return BackupResult{}, fmt.Errorf("restore/snapshot: %w", err)
+	}
+	if len(results) != 1 {
+		return BackupResult{}, fmt.Errorf("restore/snapshot: expected 1 result, got %d", len(results))
+	}
+	return results[0], nil
+}
+
+// undoCommand is the restore command printed after a snapshot. Only local
+// backups can be passed to --from, so snapshots in other storages are piped
+// through decode; stdin then carries the backup, so a protected database
+// needs --i-understand.
+func undoCommand(cfg *config.Config, db config.DatabaseConfig, restorer backup.Restorer, configPath string, snap BackupResult) string {
+	conf := ""
+	if configPath != "" {
+		conf = " -c " + configPath
+	}
+	restore := fmt.Sprintf("backupkit restore%s --db %s", conf, db.Name)
+	if u, ok := restorer.(backup.UndoRestorer); ok {
+		restore += " " + strings.Join(u.UndoArgs(), " ")
+	}
+	for _, sc := range cfg.Storage {
+		if sc.Name == db.Backup.Storage && sc.Type == "local" {
+			return fmt.Sprintf("%s --from %s", restore, snap.Dest)
+		}
+	}
+	if db.Protected {
+		restore += " --i-understand"
+	}
+	return fmt.Sprintf("backupkit decode%s --db %s --storage %s --key %s | %s --from -", conf, db.Name, db.Backup.Storage, snap.Key, restore)
+}
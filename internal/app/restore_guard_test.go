@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func withTerminal(t *testing.T, tty bool) {
+	t.Helper()
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return tty }
+	t.Cleanup(func() { stdinIsTerminal = orig })
+}
+
+func TestGuardProtectedRestoreRequiresConfirmationWithoutTTY(t *testing.T) {
+	withTerminal(t, false)
+	db := &config.DatabaseConfig{Name: "prod", Protected: true}
+
+	err := guardProtectedRestore(db, RestoreOptions{Clean: true}, "postgresql://prod", strings.NewReader(""), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "--i-understand") {
+		t.Fatalf("expected --i-understand error, got %v", err)
+	}
+
+	if err := guardProtectedRestore(db, RestoreOptions{Clean: true, IUnderstand: true}, "postgresql://prod", strings.NewReader(""), &bytes.Buffer{}); err != nil {
+		t.Fatalf("unexpected error with --i-understand: %v", err)
+	}
+}
+
+func TestGuardProtectedRestorePromptsOnTTY(t *testing.T) {
+	withTerminal(t, true)
+	db := &config.DatabaseConfig{Name: "prod", Protected: true}
+
+	var out bytes.Buffer
+	if err := guardProtectedRestore(db, RestoreOptions{Clean: true}, "postgresql://prod", strings.NewReader("prod\n"), &out); err != nil {
+		t.Fatalf("unexpected error for matching confirmation: %v", err)
+	}
+	if !strings.Contains(out.String(), "drop and recreate objects") {
+		t.Fatalf("expected destructive warning in prompt, got %q", out.String())
+	}
+
+	err := guardProtectedRestore(db, RestoreOptions{}, "postgresql://prod", strings.NewReader("staging\n"), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "did not match") {
+		t.Fatalf("expected mismatch error, got %v", err)
+	}
+}
+
+func TestGuardProtectedRestoreIgnoresUnprotected(t *testing.T) {
+	withTerminal(t, false)
+	db := &config.DatabaseConfig{Name: "dev"}
+	if err := guardProtectedRestore(db, RestoreOptions{Clean: true}, "postgresql://dev", strings.NewReader(""), &bytes.Buffer{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGuardProtectedRestoreFromStdinNeverPrompts(t *testing.T) {
+	withTerminal(t, true)
+	db := &config.DatabaseConfig{Name: "prod", Protected: true}
+
+	var out bytes.Buffer
+	err := guardProtectedRestore(db, RestoreOptions{FromPath: "-"}, "postgresql://prod", strings.NewReader("prod\n"), &out)
+	if err == nil || !strings.Contains(err.Error(), "--i-understand") {
+		t.Fatalf("expected --i-understand error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no prompt, got %q", out.String())
+	}
+}
+
+func TestUndoCommandPipesRemoteSnapshotsThroughDecode(t *testing.T) {
+	cfg := &config.Config{Storage: []config.StorageConfig{
+		{Name: "disk", Type: "local"},
+		{Name: "bucket", Type: "s3"},
+	}}
+	db := config.DatabaseConfig{Name: "prod", Type: "postgres", Protected: true, Backup: config.BackupConfig{Storage: "disk"}}
+	snap := BackupResult{Key: "prod/prod_1.dump.gz", Dest: "/backups/prod/prod_1.dump.gz"}
+
+	if got, want := undoCommand(cfg, db, backup.PostgresRestorer{}, "backupkit.yaml", snap), "backupkit restore -c backupkit.yaml --db prod --clean --from /backups/prod/prod_1.dump.gz"; got != want {
+		t.Fatalf("unexpected local undo command:\n got %s\nwant %s", got, want)
+	}
+
+	db.Backup.Storage = "bucket"
+	snap.Dest = "s3://backups/prod/prod_1.dump.gz"
+	want := "backupkit decode -c backupkit.yaml --db prod --storage bucket --key prod/prod_1.dump.gz | backupkit restore -c backupkit.yaml --db prod --clean --i-understand --from -"
+	if got := undoCommand(cfg, db, backup.PostgresRestorer{}, "backupkit.yaml", snap); got != want {
+		t.Fatalf("unexpected s3 undo command:\n got %s\nwant %s", got, want)
+	}
+}
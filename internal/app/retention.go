@@ -19,9 +19,28 @@ type backupEntry struct {
 }
 
 func ApplyRetention(ctx context.Context, db config.DatabaseConfig, st storage.Storage, verbose bool) error {
+	oldest, err := applyRetention(ctx, db, db.Name, st, verbose)
+	if err != nil {
+		return err
+	}
+	// Globals are pruned on their own so they do not compete with the dumps for buckets.
+	if db.Postgres != nil && db.Postgres.Globals {
+		if _, err := applyRetention(ctx, db, path.Join(db.Name, globalsDir), st, verbose); err != nil {
+			return err
+		}
+	}
+	if db.Postgres != nil && db.Postgres.WALArchive && oldest != "" {
+		return pruneWAL(ctx, db, st, oldest, verbose)
+	}
+	return nil
+}
+
+// applyRetention prunes the backups stored directly under prefix and returns the
+// key of the oldest one kept, or "" when retention did not run.
+func applyRetention(ctx context.Context, db config.DatabaseConfig, prefix string, st storage.Storage, verbose bool) (string, error) {
 	r := db.Retention
 	if r.KeepDaily <= 0 && r.KeepWeekly <= 0 && r.KeepMonthly <= 0 {
-		return nil
+		return "", nil
 	}
 
 	pr, ok := st.(prunable.Prunable)
@@ -29,15 +48,15 @@ func ApplyRetention(ctx context.Context, db config.DatabaseConfig, st storage.St
 		if verbose {
 			fmt.Printf("retention: db=%s storage=%s skipped (not prunable)\n", db.Name, st.Name())
 		}
-		return nil
+		return "", nil
 	}
 
-	objects, err := pr.List(ctx, db.Name)
+	objects, err := pr.List(ctx, prefix)
 	if err != nil {
-		return fmt.Errorf("retention list: %w", err)
+		return "", fmt.Errorf("retention list: %w", err)
 	}
 	if len(objects) == 0 {
-		return nil
+		return "", nil
 	}
 
 	entries := make([]backupEntry, 0, len(objects))
@@ -59,12 +78,14 @@ func ApplyRetention(ctx context.Context, db config.DatabaseConfig, st storage.St
 	keep := selectKeep(entries, r.KeepDaily, r.KeepWeekly, r.KeepMonthly)
 
 	deleted := 0
+	oldest := ""
 	for _, e := range entries {
 		if keep[e.obj.Key] {
+			oldest = e.obj.Key
 			continue
 		}
 		if err := pr.Delete(ctx, e.obj.Key); err != nil {
-			return fmt.Errorf("retention delete: %w", err)
+			return "", fmt.Errorf("retention delete: %w", err)
 		}
 		deleted++
 	}
@@ -72,7 +93,7 @@ func ApplyRetention(ctx context.Context, db config.DatabaseConfig, st storage.St
 	if verbose {
 		fmt.Printf(
 			"retention: db=%s storage=%s kept=%d deleted=%d skipped=%d\n",
-			db.Name,
+			prefix,
 			st.Name(),
 			len(keep),
 			deleted,
@@ -80,7 +101,7 @@ func ApplyRetention(ctx context.Context, db config.DatabaseConfig, st storage.St
 		)
 	}
 
-	return nil
+	return oldest, nil
 }
 
 func selectKeep(entries []backupEntry, keepDaily, keepWeekly, keepMonthly int) map[string]bool {
@@ -138,12 +159,13 @@ func selectKeep(entries []backupEntry, keepDaily, keepWeekly, keepMonthly int) m
 
 func parseBackupTimeFromKey(key string) (time.Time, bool) {
 	base := path.Base(key)
-	// take everything up to ".dump"
-	i := strings.Index(base, ".dump")
+	// take everything up to the "Z" that ends the timestamp; the extension
+	// (.dump, .sql, ...) depends on the engine
+	i := strings.Index(base, "Z.")
 	if i <= 0 {
 		return time.Time{}, false
 	}
-	ts := base[:i]
+	ts := base[:i+1]
 
 	// Example: 20260217_224501.123456789Z
 	t, err := time.Parse("20060102_150405.000000000Z", ts)
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path"
 	"path/filepath"
 	"time"
 
@@ -17,12 +19,17 @@ import (
 const notificationTimeout = 5 * time.Second
 
 type BackupResult struct {
-	DB       string
-	Status   string
-	Bytes    int64
-	Dest     string
-	Duration time.Duration
-	Err      error
+	DB            string
+	Status        string
+	Bytes         int64
+	Key           string
+	Dest          string
+	GlobalsKey    string
+	Host          string
+	ClientVersion string
+	ServerVersion string
+	Duration      time.Duration
+	Err           error
 }
 
 // For now: the dump stream to a local file path like:
@@ -52,19 +59,21 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 		return nil, err
 	}
 
-	pg := backup.PostgresBackupper{}
 	results := make([]BackupResult, 0, len(cfg.Databases))
 
 	for _, db := range cfg.Databases {
 		started := time.Now().UTC()
+		preDone := false
 
-		if db.Type != "postgres" {
+		engine, ok := backup.EngineFor(db)
+		if !ok || engine.Backupper == nil {
 			res := BackupResult{
 				DB:       db.Name,
 				Status:   notify.StatusFailure,
 				Duration: time.Since(started),
 				Err:      fmt.Errorf("unsupported database type: %s {db: %s}", db.Type, db.Name),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
@@ -78,11 +87,49 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 				Duration: time.Since(started),
 				Err:      fmt.Errorf("db %s: storage %q not found", db.Name, db.Backup.Storage),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
 
+		db, source, err := backup.SelectPostgresHost(ctx, db, db.Connection.Prefer)
+		if err != nil {
+			res := BackupResult{
+				DB:       db.Name,
+				Status:   notify.StatusFailure,
+				Duration: time.Since(started),
+				Err:      fmt.Errorf("backup failed for %s: %w", db.Name, err),
+			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
+			results = append(results, res)
+			notifyResult(ctx, dispatcher, res, verbose)
+			return results, res.Err
+		}
+		if verbose && source.Host != "" {
+			fmt.Printf("source: db=%s host=%s\n", db.Name, source)
+		}
+
+		versions, err := backup.CheckPostgresVersions(ctx, db, os.Stdout)
+		if err != nil {
+			res := BackupResult{
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("backup failed for %s: %w", db.Name, err),
+			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
+			results = append(results, res)
+			notifyResult(ctx, dispatcher, res, verbose)
+			return results, res.Err
+		}
+		if verbose && versions.Server != "" {
+			fmt.Printf("versions: db=%s client=%s server=%s\n", db.Name, versions.Client, versions.Server)
+		}
+
 		if verbose {
 			fmt.Printf(
 				"pipeline: db=%s compression=%v encryption=%v storage=%s\n",
@@ -93,14 +140,35 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 			)
 		}
 
-		r, err := pg.Backup(ctx, db)
+		if err := runHook(ctx, db, "pre_backup", hooksOf(db).PreBackup, hookRun{Operation: "backup"}, os.Stdout); err != nil {
+			res := BackupResult{
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("backup aborted for %s: %w", db.Name, err),
+			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
+			results = append(results, res)
+			notifyResult(ctx, dispatcher, res, verbose)
+			return results, res.Err
+		}
+		preDone = true
+
+		r, err := engine.Backupper.Backup(ctx, db)
 		if err != nil {
 			res := BackupResult{
-				DB:       db.Name,
-				Status:   notify.StatusFailure,
-				Duration: time.Since(started),
-				Err:      fmt.Errorf("backup failed for %s: %w", db.Name, err),
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("backup failed for %s: %w", db.Name, err),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
@@ -108,13 +176,7 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 
 		ts := time.Now().UTC().Format("20060102_150405.000000000Z")
 
-		ext := ".dump"
-		if db.Backup.Compression {
-			ext += ".gz"
-		}
-		if db.Backup.Encryption.Enabled {
-			ext += ".enc"
-		}
+		ext := expectedBackupExt(engine.Ext, db.Backup.Compression, db.Backup.Encryption.Enabled)
 
 		key := filepath.ToSlash(filepath.Join(db.Name, ts+ext))
 
@@ -122,26 +184,23 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 		if err != nil {
 			_ = r.Close()
 			res := BackupResult{
-				DB:       db.Name,
-				Status:   notify.StatusFailure,
-				Duration: time.Since(started),
-				Err:      fmt.Errorf("open storage writer: %w", err),
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("open storage writer: %w", err),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
 
 		// Build the pipeline
-		stream := io.Reader(r)
-		var cs closeStack
-
-		if db.Backup.Compression {
-			stream = gzipReader(stream, &cs)
-		}
-		if db.Backup.Encryption.Enabled {
-			stream = encryptReader(stream, db.Backup.Encryption.Password, &cs)
-		}
+		var cs backup.CloseStack
+		stream := encodeBackupStream(r, db, &cs)
 
 		copyDone := make(chan struct{})
 		go func() {
@@ -158,80 +217,118 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 		close(copyDone)
 
 		// close order matters
-		cs.closeAll()
+		cs.CloseAll()
 		closeDumpErr := r.Close()
 		closeWriteErr := w.Close()
 
 		if copyErr != nil {
 			res := BackupResult{
-				DB:       db.Name,
-				Status:   notify.StatusFailure,
-				Bytes:    n,
-				Dest:     dest,
-				Duration: time.Since(started),
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Bytes:         n,
+				Dest:          dest,
+				Duration:      time.Since(started),
 			}
 			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 				res.Err = fmt.Errorf("backup timed out for %s: %w", db.Name, ctx.Err())
+				finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 				results = append(results, res)
 				notifyResult(ctx, dispatcher, res, verbose)
 				return results, res.Err
 			}
 			if errors.Is(ctx.Err(), context.Canceled) {
 				res.Err = fmt.Errorf("backup canceled for %s: %w", db.Name, ctx.Err())
+				finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 				results = append(results, res)
 				notifyResult(ctx, dispatcher, res, verbose)
 				return results, res.Err
 			}
 			// local writer will leave .tmp if not closed successfully; we closed it above.
 			res.Err = fmt.Errorf("write backup: %w", copyErr)
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
 		if closeDumpErr != nil {
 			res := BackupResult{
-				DB:       db.Name,
-				Status:   notify.StatusFailure,
-				Bytes:    n,
-				Dest:     dest,
-				Duration: time.Since(started),
-				Err:      fmt.Errorf("close dump stream: %w", closeDumpErr),
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Bytes:         n,
+				Dest:          dest,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("close dump stream: %w", closeDumpErr),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
 		if closeWriteErr != nil {
 			res := BackupResult{
-				DB:       db.Name,
-				Status:   notify.StatusFailure,
-				Bytes:    n,
-				Dest:     dest,
-				Duration: time.Since(started),
-				Err:      fmt.Errorf("finalize storage write: %w", closeWriteErr),
+				DB:            db.Name,
+				Status:        notify.StatusFailure,
+				Host:          source.Addr(),
+				ClientVersion: versions.Client,
+				ServerVersion: versions.Server,
+				Bytes:         n,
+				Dest:          dest,
+				Duration:      time.Since(started),
+				Err:           fmt.Errorf("finalize storage write: %w", closeWriteErr),
 			}
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
 
 		res := BackupResult{
-			DB:       db.Name,
-			Status:   notify.StatusSuccess,
-			Bytes:    n,
-			Dest:     dest,
-			Duration: time.Since(started),
+			DB:            db.Name,
+			Status:        notify.StatusSuccess,
+			Host:          source.Addr(),
+			ClientVersion: versions.Client,
+			ServerVersion: versions.Server,
+			Bytes:         n,
+			Key:           key,
+			Dest:          dest,
+			Duration:      time.Since(started),
+		}
+
+		if db.Postgres != nil && db.Postgres.Globals {
+			gkey, gdest, err := backupGlobals(ctx, db, st, ts)
+			if err != nil {
+				res.Status = notify.StatusFailure
+				res.Err = fmt.Errorf("globals backup failed for %s: %w", db.Name, err)
+				finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
+				results = append(results, res)
+				notifyResult(ctx, dispatcher, res, verbose)
+				return results, res.Err
+			}
+			res.GlobalsKey = gkey
+			fmt.Printf("globals OK: db=%s dest=%s\n", db.Name, gdest)
 		}
 
 		// after successful backup
 		if err := ApplyRetention(ctx, db, st, verbose); err != nil {
 			res.Status = notify.StatusFailure
 			res.Err = fmt.Errorf("retention failed for %s: %w", db.Name, err)
+			finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 			results = append(results, res)
 			notifyResult(ctx, dispatcher, res, verbose)
 			return results, res.Err
 		}
+		finishBackupHooks(ctx, db, &res, preDone, os.Stdout)
 		results = append(results, res)
+		if res.Err != nil {
+			notifyResult(ctx, dispatcher, res, verbose)
+			return results, res.Err
+		}
 
 		fmt.Printf("backup OK: db=%s bytes=%d dest=%s duration=%s\n", db.Name, n, dest, res.Duration.Round(time.Millisecond))
 		notifyResult(ctx, dispatcher, res, verbose)
@@ -241,6 +338,64 @@ func RunBackupWithResults(ctx context.Context, cfg *config.Config, verbose bool)
 	return results, nil
 }
 
+// globalsDir holds a database's pg_dumpall --globals-only backups.
+const globalsDir = "globals"
+
+// backupGlobals stores the cluster globals as <db>/globals/<ts>.sql through the
+// same compression and encryption as the database dump.
+func backupGlobals(ctx context.Context, db config.DatabaseConfig, st storage.Storage, ts string) (string, string, error) {
+	r, err := backup.PostgresGlobalsBackupper{}.Backup(ctx, db)
+	if err != nil {
+		return "", "", err
+	}
+
+	ext := expectedBackupExt(".sql", db.Backup.Compression, db.Backup.Encryption.Enabled)
+	key := path.Join(db.Name, globalsDir, ts+ext)
+
+	w, dest, err := st.OpenWriter(ctx, key)
+	if err != nil {
+		_ = r.Close()
+		return "", "", fmt.Errorf("open storage writer: %w", err)
+	}
+
+	var cs backup.CloseStack
+	_, copyErr := io.Copy(w, encodeBackupStream(r, db, &cs))
+	cs.CloseAll()
+	closeDumpErr := r.Close()
+	closeWriteErr := w.Close()
+
+	switch {
+	case copyErr != nil:
+		return "", dest, fmt.Errorf("write backup: %w", copyErr)
+	case closeDumpErr != nil:
+		return "", dest, fmt.Errorf("close dump stream: %w", closeDumpErr)
+	case closeWriteErr != nil:
+		return "", dest, fmt.Errorf("finalize storage write: %w", closeWriteErr)
+	}
+	return key, dest, nil
+}
+
+// backupperFor returns the registered backupper for a database.
+func backupperFor(db config.DatabaseConfig) (backup.Backupper, bool) {
+	engine, ok := backup.EngineFor(db)
+	if !ok || engine.Backupper == nil {
+		return nil, false
+	}
+	return engine.Backupper, true
+}
+
+// encodeBackupStream applies the configured compression and encryption stages.
+func encodeBackupStream(r io.Reader, db config.DatabaseConfig, cs *backup.CloseStack) io.Reader {
+	stream := r
+	if db.Backup.Compression {
+		stream = gzipReader(stream, cs)
+	}
+	if db.Backup.Encryption.Enabled {
+		stream = encryptReader(stream, db.Backup.Encryption.Password, cs)
+	}
+	return stream
+}
+
 func notifyResult(ctx context.Context, dispatcher *notify.Dispatcher, res BackupResult, verbose bool) {
 	errMsg := ""
 	if res.Err != nil {
@@ -252,6 +407,7 @@ func notifyResult(ctx context.Context, dispatcher *notify.Dispatcher, res Backup
 		Status:   res.Status,
 		Bytes:    res.Bytes,
 		Dest:     res.Dest,
+		Host:     res.Host,
 		Duration: res.Duration.Round(time.Millisecond).String(),
 		Error:    errMsg,
 	}
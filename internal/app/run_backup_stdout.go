@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+	"github.com/dev-tams/backupkit/internal/notify"
+	"golang.org/x/term"
+)
+
+// stdoutIsTerminal guards against dumping a binary backup onto a terminal; replaced in tests.
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// RunBackupToStdout runs the configured dump/compress/encrypt pipeline for one
+// database and writes it to stdout instead of a storage backend. Logs go to
+// stderr. Retention and notifications are skipped: nothing is stored. Hooks
+// run as for a stored backup, with BACKUPKIT_DEST=stdout.
+func RunBackupToStdout(ctx context.Context, cfg *config.Config, dbName string, verbose bool) error {
+	if stdoutIsTerminal() {
+		return fmt.Errorf("backup: refusing to write a backup to a terminal; redirect or pipe stdout")
+	}
+	return runBackupToWriter(ctx, cfg, dbName, backupperFor, os.Stdout, os.Stderr, verbose)
+}
+
+func runBackupToWriter(
+	ctx context.Context,
+	cfg *config.Config,
+	dbName string,
+	lookup func(config.DatabaseConfig) (backup.Backupper, bool),
+	w io.Writer,
+	logw io.Writer,
+	verbose bool,
+) (err error) {
+	db, err := pickDatabase(cfg, dbName)
+	if err != nil {
+		return err
+	}
+	res := BackupResult{DB: db.Name, Dest: "stdout", Status: notify.StatusSuccess}
+	preDone := false
+	defer func() {
+		if err != nil {
+			res.Status, res.Err = notify.StatusFailure, err
+		}
+		finishBackupHooks(ctx, *db, &res, preDone, logw)
+		err = res.Err
+	}()
+	b, ok := lookup(*db)
+	if !ok {
+		return fmt.Errorf("unsupported database type: %s {db: %s}", db.Type, db.Name)
+	}
+	chosen, source, err := backup.SelectPostgresHost(ctx, *db, db.Connection.Prefer)
+	if err != nil {
+		return fmt.Errorf("backup failed for %s: %w", db.Name, err)
+	}
+	db = &chosen
+	if verbose && source.Host != "" {
+		fmt.Fprintf(logw, "source: db=%s host=%s\n", db.Name, source)
+	}
+	if _, err := backup.CheckPostgresVersions(ctx, *db, logw); err != nil {
+		return fmt.Errorf("backup failed for %s: %w", db.Name, err)
+	}
+
+	started := time.Now().UTC()
+	if verbose {
+		fmt.Fprintf(
+			logw,
+			"pipeline: db=%s compression=%v encryption=%v storage=stdout\n",
+			db.Name,
+			db.Backup.Compression,
+			db.Backup.Encryption.Enabled,
+		)
+	}
+
+	if err := runHook(ctx, *db, "pre_backup", hooksOf(*db).PreBackup, hookRun{Operation: "backup"}, logw); err != nil {
+		return fmt.Errorf("backup aborted for %s: %w", db.Name, err)
+	}
+	preDone = true
+
+	r, err := b.Backup(ctx, *db)
+	if err != nil {
+		return fmt.Errorf("backup failed for %s: %w", db.Name, err)
+	}
+
+	var cs backup.CloseStack
+	stream := encodeBackupStream(r, *db, &cs)
+
+	n, copyErr := io.Copy(w, stream)
+	cs.CloseAll()
+	closeDumpErr := r.Close()
+	res.Bytes = n
+
+	if copyErr != nil {
+		return fmt.Errorf("write backup: %w", copyErr)
+	}
+	if closeDumpErr != nil {
+		return fmt.Errorf("close dump stream: %w", closeDumpErr)
+	}
+
+	fmt.Fprintf(logw, "backup OK: db=%s bytes=%d dest=stdout duration=%s\n", db.Name, n, time.Since(started).Round(time.Millisecond))
+	return nil
+}
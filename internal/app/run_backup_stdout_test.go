@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+type staticBackupper struct{ payload []byte }
+
+func (b staticBackupper) Backup(context.Context, config.DatabaseConfig) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b.payload)), nil
+}
+
+func TestRunBackupToWriterEncodesConfiguredPipeline(t *testing.T) {
+	cfg := &config.Config{Databases: []config.DatabaseConfig{
+		{Name: "other", Type: "postgres"},
+		{
+			Name: "app_db",
+			Type: "postgres",
+			Backup: config.BackupConfig{
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+		},
+	}}
+	payload := append([]byte("PGDMP"), bytes.Repeat([]byte("row"), 500)...)
+
+	var out, logs bytes.Buffer
+	if err := runBackupToWriter(context.Background(), cfg, "app_db", func(config.DatabaseConfig) (backup.Backupper, bool) {
+		return staticBackupper{payload}, true
+	}, &out, &logs, false); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.Contains(logs.String(), "backup OK: db=app_db") || !strings.Contains(logs.String(), "dest=stdout") {
+		t.Fatalf("unexpected log output %q", logs.String())
+	}
+
+	raw := bufio.NewReader(&out)
+	rawKind, err := sniffRawKind(raw)
+	if err != nil || rawKind != "enc" {
+		t.Fatalf("expected encrypted stream, got %q (%v)", rawKind, err)
+	}
+	var cs backup.CloseStack
+	defer cs.CloseAll()
+	br, innerKind, err := decodeStream(raw, rawKind, &cfg.Databases[1], &cs)
+	if err != nil || innerKind != "gzip" {
+		t.Fatalf("decode: inner=%q err=%v", innerKind, err)
+	}
+	got, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("read decoded: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d", len(got), len(payload))
+	}
+}
+
+func TestRunBackupToStdoutRefusesTerminal(t *testing.T) {
+	orig := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdoutIsTerminal = orig })
+
+	err := RunBackupToStdout(context.Background(), &config.Config{}, "", false)
+	if err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Fatalf("expected terminal refusal, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func encodeBackup(t *testing.T, payload []byte, password string) []byte {
+	t.Helper()
+	var cs backup.CloseStack
+	defer cs.CloseAll()
+	stream := gzipReader(bytes.NewReader(payload), &cs)
+	stream = encryptReader(stream, password, &cs)
+	out, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return out
+}
+
+func decodeTestConfig(storePath string) *config.Config {
+	return &config.Config{
+		Databases: []config.DatabaseConfig{{
+			Name: "app_db",
+			Type: "postgres",
+			Backup: config.BackupConfig{
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+		}},
+		Storage: []config.StorageConfig{{
+			Name:  "local",
+			Type:  "local",
+			Local: &config.LocalConfig{Path: storePath},
+		}},
+	}
+}
+
+func TestRunDecodeWritesPlainArchive(t *testing.T) {
+	dir := t.TempDir()
+	payload := append([]byte("PGDMP"), bytes.Repeat([]byte{1, 2, 3}, 1000)...)
+	key := "app_db/20260218_120000.000000000Z.dump.gz.enc"
+	if err := os.MkdirAll(filepath.Join(dir, "app_db"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(key)), encodeBackup(t, payload, "secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := decodeTestConfig(dir)
+
+	for name, opts := range map[string]DecodeOptions{
+		"file":    {FromPath: filepath.Join(dir, filepath.FromSlash(key))},
+		"storage": {Storage: "local", Key: key},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts.OutPath = filepath.Join(t.TempDir(), "out.dump")
+			if err := RunDecode(context.Background(), cfg, opts); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got, err := os.ReadFile(opts.OutPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Fatalf("decoded %d bytes, want %d", len(got), len(payload))
+			}
+		})
+	}
+}
+
+func TestRunDecodeWrongPasswordLeavesNoOutput(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "backup.dump.gz.enc")
+	if err := os.WriteFile(in, encodeBackup(t, []byte("PGDMP-payload"), "other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.dump")
+
+	err := RunDecode(context.Background(), decodeTestConfig(dir), DecodeOptions{FromPath: in, OutPath: out})
+	if err == nil {
+		t.Fatal("expected decode error with wrong password")
+	}
+	if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no output file, stat err=%v", statErr)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Fatalf("temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestDecodeOptionsValidate(t *testing.T) {
+	cases := []DecodeOptions{
+		{},
+		{Storage: "local"},
+		{FromPath: "x", Key: "k"},
+	}
+	for _, opts := range cases {
+		if err := opts.validate(); err == nil {
+			t.Fatalf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestRunDecodeUnknownStreamNamesExpectedKind(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "queues.rdb")
+	if err := os.WriteFile(in, bytes.Repeat([]byte{0xff, 0x00}, 64), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := decodeTestConfig(dir)
+	cfg.Databases[0] = config.DatabaseConfig{Name: "queues", Type: "redis", Backup: config.BackupConfig{Storage: "local"}}
+
+	err := RunDecode(context.Background(), cfg, DecodeOptions{DBName: "queues", FromPath: in, OutPath: filepath.Join(dir, "out.rdb")})
+	if err == nil || !strings.Contains(err.Error(), "expected rdb stream") {
+		t.Fatalf("expected an error naming the rdb stream, got %v", err)
+	}
+}
@@ -8,11 +8,11 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dev-tams/backupkit/internal/backup"
 	"github.com/dev-tams/backupkit/internal/config"
 )
 
@@ -20,310 +20,489 @@ var (
 	encMagic   = []byte("BKENC001")
 	gzipMagic  = []byte{0x1f, 0x8b}
 	pgdmpMagic = []byte("PGDMP")
-	execLookPath = exec.LookPath
 )
 
-func RunRestore(
-	ctx context.Context,
-	cfg *config.Config,
-	dbName string,
-	fromPath string,
-	verbose bool,
-	clean bool,
-	strictSniff bool,
-	allowSQLFallback bool,
-) error {
-	// pick db
-	var db *config.DatabaseConfig
-	if dbName == "" {
-		if len(cfg.Databases) == 0 {
-			return fmt.Errorf("no databases configured")
+// fileMagics identify engine formats that are restored as-is rather than
+// through pg_restore or a SQL client.
+var fileMagics = []struct {
+	kind  string
+	magic []byte
+}{
+	{"sqlite", []byte("SQLite format 3\x00")},
+	{"mongo", []byte{0x6d, 0xe2, 0x99, 0x81}}, // mongodump --archive
+	{"rdb", []byte("REDIS")},
+}
+
+// RestoreOptions controls how RunRestore decodes a backup and what it applies.
+type RestoreOptions struct {
+	DBName           string
+	FromPath         string
+	Verbose          bool
+	Clean            bool
+	StrictSniff      bool
+	AllowSQLFallback bool
+
+	// Selective restore; only honored for pg_dump archives.
+	Schemas    []string
+	Tables     []string
+	SchemaOnly bool
+	DataOnly   bool
+	// UseList is a TOC list file (as printed by List) passed to pg_restore --use-list.
+	UseList string
+	// List prints the archive TOC via pg_restore --list instead of restoring.
+	List bool
+
+	// Ownership handling. NoOwner, NoPrivileges and Role map to the pg_restore flags of
+	// the same name; RoleMap ("old=new") renames roles in OWNER TO / GRANT / REVOKE and
+	// forces a pg_restore --file=- | psql restore so the SQL can be rewritten.
+	NoOwner      bool
+	NoPrivileges bool
+	Role         string
+	RoleMap      []string
+
+	// IUnderstand confirms a restore into a database marked protected in config.
+	IUnderstand bool
+	// Snapshot backs up the target before restoring; destructive restores also
+	// snapshot when the database sets snapshot_before_restore.
+	Snapshot bool
+
+	// DryRun sniffs the backup, checks tools and prints the restore plan without touching the database.
+	DryRun bool
+
+	// Progress ("text" or "json") prints bytes read, throughput, ETA and the current
+	// TOC entry to stderr every ProgressInterval.
+	Progress         string
+	ProgressInterval time.Duration
+
+	// Jobs > 1 decodes the archive into a temp file under TempDir and runs pg_restore -j Jobs.
+	Jobs    int
+	TempDir string
+
+	// OutPath is the file written for engines that restore to a file (redis).
+	OutPath string
+
+	// TargetTime (RFC 3339) is the point in time a physical restore replays the
+	// WAL archive to; ConfigPath is passed to the wal-fetch restore_command.
+	TargetTime string
+	ConfigPath string
+
+	// Globals is a postgres globals backup (<db>/globals/<ts>.sql...) applied with
+	// psql before the database restore.
+	Globals string
+}
+
+func (o RestoreOptions) wantsSnapshot(db *config.DatabaseConfig) bool {
+	return o.Snapshot || (o.Clean && db.SnapshotBeforeRestore)
+}
+
+// engineOptions are the options handed to the database engine's restorer.
+func (o RestoreOptions) engineOptions() backup.RestoreOptions {
+	return backup.RestoreOptions{
+		Clean:            o.Clean,
+		AllowSQLFallback: o.AllowSQLFallback,
+		Verbose:          o.Verbose,
+		Schemas:          o.Schemas,
+		Tables:           o.Tables,
+		SchemaOnly:       o.SchemaOnly,
+		DataOnly:         o.DataOnly,
+		UseList:          o.UseList,
+		NoOwner:          o.NoOwner,
+		NoPrivileges:     o.NoPrivileges,
+		Role:             o.Role,
+		RoleMap:          o.RoleMap,
+		Jobs:             o.Jobs,
+		TempDir:          o.TempDir,
+		OutPath:          o.OutPath,
+	}
+}
+
+func (o RestoreOptions) validate() error {
+	if o.SchemaOnly && o.DataOnly {
+		return fmt.Errorf("restore: --schema-only and --data-only cannot be used together")
+	}
+	for _, s := range o.Schemas {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("restore: --schema must not be empty")
 		}
-		db = &cfg.Databases[0]
-	} else {
-		for i := range cfg.Databases {
-			if cfg.Databases[i].Name == dbName {
-				db = &cfg.Databases[i]
-				break
-			}
+	}
+	for _, t := range o.Tables {
+		if strings.TrimSpace(t) == "" {
+			return fmt.Errorf("restore: --table must not be empty")
+		}
+	}
+	switch o.Progress {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("restore: --progress-format must be text or json, got %q", o.Progress)
+	}
+	if o.Jobs < 0 {
+		return fmt.Errorf("restore: --jobs must be >= 0")
+	}
+	if o.TargetTime != "" {
+		if _, err := time.Parse(time.RFC3339, o.TargetTime); err != nil {
+			return fmt.Errorf("restore: --target-time must be RFC 3339, e.g. 2026-03-01T14:30:00Z: %w", err)
+		}
+	}
+	if o.Globals != "" {
+		if o.List {
+			return fmt.Errorf("restore: --list and --globals cannot be used together")
+		}
+		if o.Globals == "-" || o.FromPath == "-" {
+			return fmt.Errorf("restore: --globals needs files for both backups, not stdin")
+		}
+	}
+	if o.UseList != "" {
+		if o.List {
+			return fmt.Errorf("restore: --list and --use-list cannot be used together")
 		}
-		if db == nil {
-			return fmt.Errorf("db %q not found in config", dbName)
+		if _, err := os.Stat(o.UseList); err != nil {
+			return fmt.Errorf("restore: --use-list: %w", err)
 		}
 	}
+	return nil
+}
 
-	if db.Type != "postgres" {
-		return fmt.Errorf("unsupported database type: %s {db: %s}", db.Name, db.Type)
+// RunRestore decodes a backup and hands it to the restorer registered for the
+// database's type. Guard, snapshot, dry-run reporting and progress are shared by
+// all engines.
+func RunRestore(ctx context.Context, cfg *config.Config, opts RestoreOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
 	}
 
-	f, err := os.Open(fromPath)
+	db, err := pickDatabase(cfg, opts.DBName)
 	if err != nil {
-		return fmt.Errorf("restore/open: %w", err)
+		return err
 	}
-	defer f.Close()
 
-	rawKind, err := sniffRawKind(f)
-	if err != nil {
-		return fmt.Errorf("restore/sniff: %w", err)
+	engine, ok := backup.EngineFor(*db)
+	if !ok || engine.Restorer == nil {
+		return fmt.Errorf("unsupported database type: %s {db: %s}", db.Name, db.Type)
 	}
+	restorer := engine.Restorer
 
-	expectedRaw := expectedRawKind(db.Backup.Compression, db.Backup.Encryption.Enabled)
-	if rawKind != expectedRaw {
-		msg := fmt.Sprintf(
-			"backup header mismatch for db=%s: expected %q from config, got %q (%s)",
-			db.Name,
-			expectedRaw,
-			rawKind,
-			fromPath,
-		)
-		if strictSniff {
-			return fmt.Errorf("restore/sniff: %s", msg)
+	var lister backup.Lister
+	if opts.List {
+		if lister, ok = restorer.(backup.Lister); !ok {
+			return fmt.Errorf("restore: --list not supported for %s databases", db.Type)
 		}
-		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
 	}
 
-	// Suffix mismatch is non-fatal; restore continues with a warning.
-	expectedExt := expectedBackupExt(db.Backup.Compression, db.Backup.Encryption.Enabled)
-	gotExt := backupSuffix(filepath.Base(fromPath))
-	if gotExt != expectedExt {
-		fmt.Fprintf(
-			os.Stderr,
-			"warning: backup suffix mismatch for db=%s: expected %q from config, got %q (%s)\n",
-			db.Name,
-			expectedExt,
-			gotExt,
-			fromPath,
-		)
+	// File restorers hand the backup to the operator; the database is not written.
+	fileRestorer, toFile := restorer.(backup.FileRestorer)
+	if opts.OutPath != "" && !toFile {
+		return fmt.Errorf("restore: --out not supported for %s databases", db.Type)
 	}
 
-	// reverse pipeline: decrypt -> gunzip
-	stream := io.Reader(f)
-	var cs closeStack
-
-	// Build decode stages from file bytes, not config, so restore follows actual payload.
-	switch rawKind {
-	case "enc":
-		if db.Backup.Encryption.Password == "" {
-			return fmt.Errorf("restore/decrypt: encryption password is empty (db=%s)", db.Name)
-		}
-		stream = decryptReader(stream, db.Backup.Encryption.Password, &cs)
-	case "gzip":
-		stream = gunzipReader(stream, &cs)
-	case "pgdmp":
-		// no transform
-	case "unknown":
-		if !allowSQLFallback {
-			return fmt.Errorf("restore/sniff: unrecognized backup header; rerun with --allow-sql-fallback if this may be a plain SQL dump")
-		}
-	default:
-		return fmt.Errorf("restore/sniff: unsupported raw stream kind %q", rawKind)
+	if _, ok := restorer.(backup.GlobalsRestorer); opts.Globals != "" && !ok {
+		return fmt.Errorf("restore: --globals not supported for %s databases", db.Type)
+	}
+	walRestorer, pitr := restorer.(backup.WALRestorer)
+	if opts.TargetTime != "" && !pitr {
+		return fmt.Errorf("restore: --target-time needs a postgres database with mode physical")
 	}
 
-	br := bufio.NewReader(stream)
-	innerKind := rawKind
-	if rawKind == "enc" {
-		innerKind, err = sniffLeadingKind(br)
+	// Restores write, so they need a primary; --list, --out and --dry-run do
+	// not connect.
+	if !opts.List && !toFile && !opts.DryRun {
+		chosen, source, err := backup.SelectPostgresHost(ctx, *db, "primary")
 		if err != nil {
-			cs.closeAll()
-			return fmt.Errorf("restore/sniff: %w", err)
+			return fmt.Errorf("restore: %w", err)
 		}
-		if innerKind == "gzip" {
-			stream = gunzipReader(br, &cs)
-			br = bufio.NewReader(stream)
+		db = &chosen
+		if opts.Verbose && source.Host != "" {
+			fmt.Fprintf(os.Stderr, "target: db=%s host=%s\n", db.Name, source)
 		}
 	}
 
-	decodedKind, err := sniffDecodedKind(br)
+	in, err := openRestoreInput(db, engine, opts)
 	if err != nil {
-		cs.closeAll()
-		return fmt.Errorf("restore/sniff: %w", err)
-	}
-	switch decodedKind {
-	case "pgdmp":
-	case "sql":
-		if !allowSQLFallback {
-			cs.closeAll()
-			return fmt.Errorf("restore/sniff: decoded stream looks like SQL text; rerun with --allow-sql-fallback to restore with psql")
-		}
-	default:
-		cs.closeAll()
-		return fmt.Errorf("restore/sniff: decoded stream is neither pg_dump custom format nor recognizable SQL text")
+		return err
 	}
-	stream = br
-
-	conn := db.Connection
+	defer in.file.Close()
+	// Engines close their own pipes; this stops the decode stages if they return early.
+	defer in.cs.CloseAll()
 
-	if verbose {
-		fmt.Printf(
-			"restore pipeline: db=%s raw=%s inner=%s tool=pg_restore clean=%v\n",
-			db.Name,
-			rawKind,
-			innerKind,
-			clean,
-		)
+	var globals *restoreInput
+	if opts.Globals != "" {
+		if globals, err = openGlobalsInput(db, opts); err != nil {
+			return err
+		}
+		defer globals.file.Close()
+		defer globals.cs.CloseAll()
 	}
 
-	if decodedKind == "sql" {
-		if err := validateRestoreToolAvailability(decodedKind); err != nil {
-			cs.closeAll()
+	req := backup.RestoreRequest{
+		DB:         *db,
+		From:       opts.FromPath,
+		Kind:       in.decodedKind,
+		Stream:     in.br,
+		StoredSize: in.storedSize,
+		Options:    opts.engineOptions(),
+		Out:        os.Stdout,
+	}
+	if globals != nil {
+		req.Globals = globals.br
+		req.GlobalsFrom = opts.Globals
+	}
+	if pitr && walRestorer.ReplaysWAL(*db) {
+		if req.Options.WALFetch, err = walFetchArgv(opts.ConfigPath, db.Name); err != nil {
 			return err
 		}
-		if clean {
-			fmt.Fprintln(os.Stderr, "warning: --clean is ignored when falling back to psql")
-		}
-		if verbose {
-			fmt.Printf("restore tool fallback: db=%s tool=psql\n", db.Name)
-		}
-		args := []string{
-			"--host", conn.Host,
-			"--port", strconv.Itoa(conn.Port),
-			"--dbname", conn.Database,
-			"--username", conn.User,
-			"-v", "ON_ERROR_STOP=1",
-		}
-		return runSQLRestore(ctx, args, conn.Password, stream, &cs, db.Name, fromPath)
 	}
-
-	if err := validateRestoreToolAvailability(decodedKind); err != nil {
-		cs.closeAll()
+	if opts.TargetTime != "" {
+		t, _ := time.Parse(time.RFC3339, opts.TargetTime)
+		req.Options.TargetTime = t.UTC().Format("2006-01-02 15:04:05.999999") + "+00"
+	}
+	if err := restorer.Check(ctx, req); err != nil {
 		return err
 	}
 
-	args := []string{
-		"--host", conn.Host,
-		"--port", strconv.Itoa(conn.Port),
-		"--dbname", conn.Database,
-		"--username", conn.User,
-		"--format=custom",
-		"--exit-on-error",
-	}
-	if clean {
-		args = append(args, "--clean", "--if-exists")
+	if lister != nil {
+		return lister.List(ctx, req, os.Stdout)
 	}
 
-	cmd := exec.CommandContext(ctx, "pg_restore", args...)
+	target := restorer.Target(*db)
 
-	// password env
-	if conn.Password != "" {
-		cmd.Env = append(os.Environ(), "PGPASSWORD="+conn.Password)
-	} else {
-		cmd.Env = os.Environ()
+	if opts.DryRun {
+		w := os.Stdout
+		fmt.Fprintf(w, "restore plan (dry run): db=%s\n", db.Name)
+		if in.storedSize > 0 {
+			fmt.Fprintf(w, "  source:   %s (%d bytes)\n", opts.FromPath, in.storedSize)
+		} else {
+			fmt.Fprintf(w, "  source:   %s (size unknown)\n", opts.FromPath)
+		}
+		fmt.Fprintf(w, "  pipeline: raw=%s inner=%s decoded=%s\n", in.rawKind, in.innerKind, in.decodedKind)
+		fmt.Fprintf(w, "  target:   %s\n", target)
+		if hosts := db.Connection.Hosts; len(hosts) > 0 && !toFile {
+			fmt.Fprintf(w, "  hosts:    %s (not probed; the restore uses the primary)\n", strings.Join(hosts, ", "))
+		}
+		if toFile {
+			fmt.Fprintf(w, "  output:   %s (the database is not contacted)\n", fileRestorer.OutputPath(req))
+		}
+		if db.Protected && !opts.IUnderstand && !toFile {
+			fmt.Fprintln(w, "  guard:    db is protected; restore requires --i-understand or interactive confirmation")
+		}
+		if opts.wantsSnapshot(db) && !toFile {
+			fmt.Fprintf(w, "  snapshot: target is backed up to storage %s before restoring\n", db.Backup.Storage)
+		}
+		if err := restorer.Plan(ctx, req, w); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "dry run: database not modified")
+		return nil
 	}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		cs.closeAll()
-		return fmt.Errorf("restore/pg_restore/stdin: %w", err)
+	if !toFile {
+		if err := guardProtectedRestore(db, opts, target, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
 	}
 
-	if err := cmd.Start(); err != nil {
-		_ = stdin.Close()
-		cs.closeAll()
-		return fmt.Errorf("restore/pg_restore/start: %w", err)
+	if opts.wantsSnapshot(db) && !toFile {
+		snap, err := snapshotBeforeRestore(ctx, cfg, *db, opts.Verbose)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("snapshot OK: db=%s key=%s dest=%s\n", db.Name, snap.Key, snap.Dest)
+		fmt.Printf("to undo this restore: %s\n", undoCommand(cfg, *db, restorer, opts.ConfigPath, snap))
 	}
 
-	_, copyErr := io.Copy(stdin, stream)
-	_ = stdin.Close()
+	run := hookRun{Operation: "restore", From: opts.FromPath}
+	if toFile {
+		run.Dest = fileRestorer.OutputPath(req)
+	}
+	if err := runHook(ctx, *db, "pre_restore", hooksOf(*db).PreRestore, run, os.Stdout); err != nil {
+		err = fmt.Errorf("restore aborted for %s: %w", db.Name, err)
+		return finishRestoreHooks(ctx, *db, run, false, err, os.Stdout)
+	}
 
-	// close pipeline readers (pipe readers) after streaming completes
-	cs.closeAll()
+	prog := newProgressReporter(db.Name, in.storedSize, in.counter, opts.Progress, opts.ProgressInterval, os.Stderr)
+	if prog != nil {
+		req.Progress = prog
+	}
+	prog.start()
+	defer prog.stop()
 
-	waitErr := cmd.Wait()
+	if opts.Verbose {
+		fmt.Printf(
+			"restore pipeline: db=%s type=%s raw=%s inner=%s decoded=%s clean=%v\n",
+			db.Name,
+			db.Type,
+			in.rawKind,
+			in.innerKind,
+			in.decodedKind,
+			opts.Clean,
+		)
+	}
+	err = restorer.Restore(ctx, req)
+	prog.stop()
+	run.Bytes = in.counter.Count()
+	return finishRestoreHooks(ctx, *db, run, true, err, os.Stdout)
+}
 
-	if copyErr != nil {
-		return fmt.Errorf("restore/stream: %w", copyErr)
+// openGlobalsInput decodes the --globals backup, which must be SQL text.
+func openGlobalsInput(db *config.DatabaseConfig, opts RestoreOptions) (*restoreInput, error) {
+	gopts := opts
+	gopts.FromPath = opts.Globals
+	in, err := openRestoreInput(db, backup.Engine{Ext: ".sql", Header: "unknown"}, gopts)
+	if err != nil {
+		return nil, err
 	}
-	if waitErr != nil {
-		pgErr := strings.TrimSpace(stderr.String())
-		if strings.Contains(pgErr, "already exists") {
-			return fmt.Errorf(
-				"restore/pg_restore/wait: %w: %s\nhint: target database is not empty. rerun with --clean or restore into a fresh database",
-				waitErr,
-				pgErr,
-			)
-		}
-		return fmt.Errorf("restore/pg_restore/wait: %w: %s", waitErr, pgErr)
+	if in.decodedKind != "sql" {
+		in.cs.CloseAll()
+		_ = in.file.Close()
+		return nil, fmt.Errorf("restore/globals: %s decoded to %s, expected pg_dumpall SQL text", opts.Globals, in.decodedKind)
 	}
+	return in, nil
+}
 
-	fmt.Printf("restore OK: db=%s from=%s\n", db.Name, fromPath)
-	return nil
+// restoreInput is a backup opened for restore with encryption and compression reversed.
+type restoreInput struct {
+	file       *os.File
+	counter    *countingReader
+	storedSize int64
+	rawKind    string
+	innerKind  string
+	// decodedKind is sniffed but not checked; engines decide what they accept.
+	decodedKind string
+	br          *bufio.Reader
+	cs          backup.CloseStack
 }
 
-func runSQLRestore(
-	ctx context.Context,
-	args []string,
-	password string,
-	stream io.Reader,
-	cs *closeStack,
-	dbName string,
-	fromPath string,
-) error {
-	cmd := exec.CommandContext(ctx, "psql", args...)
-	if password != "" {
-		cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
-	} else {
-		cmd.Env = os.Environ()
-	}
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	stdin, err := cmd.StdinPipe()
+// openRestoreInput opens opts.FromPath, warns (or with --strict-sniff fails) when
+// the header or suffix disagrees with db's config, and decodes the stream.
+func openRestoreInput(db *config.DatabaseConfig, engine backup.Engine, opts RestoreOptions) (*restoreInput, error) {
+	f, storedSize, err := openRestoreSource(opts.FromPath)
 	if err != nil {
-		return fmt.Errorf("restore/psql/stdin: %w", err)
+		return nil, err
+	}
+	in := &restoreInput{file: f, storedSize: storedSize, counter: &countingReader{r: f}}
+	fail := func(err error) (*restoreInput, error) {
+		in.cs.CloseAll()
+		_ = f.Close()
+		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
-		_ = stdin.Close()
-		return fmt.Errorf("restore/psql/start: %w", err)
+	raw := bufio.NewReader(in.counter)
+	if in.rawKind, err = sniffRawKind(raw); err != nil {
+		return fail(fmt.Errorf("restore/sniff: %w", err))
 	}
 
-	_, copyErr := io.Copy(stdin, stream)
-	_ = stdin.Close()
-	cs.closeAll()
+	expectedRaw := expectedRawKind(engine.Header, db.Backup.Compression, db.Backup.Encryption.Enabled)
+	if expectedRaw != "" && in.rawKind != expectedRaw {
+		msg := fmt.Sprintf(
+			"backup header mismatch for db=%s: expected %q from config, got %q (%s)",
+			db.Name,
+			expectedRaw,
+			in.rawKind,
+			opts.FromPath,
+		)
+		if opts.StrictSniff {
+			return fail(fmt.Errorf("restore/sniff: %s", msg))
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	}
 
-	waitErr := cmd.Wait()
+	// Suffix mismatch is non-fatal; restore continues with a warning.
+	expectedExt := expectedBackupExt(engine.Ext, db.Backup.Compression, db.Backup.Encryption.Enabled)
+	gotExt := backupSuffix(filepath.Base(opts.FromPath))
+	if opts.FromPath != "-" && gotExt != expectedExt {
+		fmt.Fprintf(
+			os.Stderr,
+			"warning: backup suffix mismatch for db=%s: expected %q from config, got %q (%s)\n",
+			db.Name,
+			expectedExt,
+			gotExt,
+			opts.FromPath,
+		)
+	}
 
-	if copyErr != nil {
-		return fmt.Errorf("restore/stream: %w", copyErr)
+	// Unrecognized raw headers pass through; the decoded sniff decides whether
+	// the stream is an archive or SQL text.
+	if in.br, in.innerKind, err = decodeStream(raw, in.rawKind, db, &in.cs); err != nil {
+		return fail(fmt.Errorf("restore/%w", err))
 	}
-	if waitErr != nil {
-		return fmt.Errorf("restore/psql/wait: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	if in.decodedKind, err = sniffDecodedKind(in.br); err != nil {
+		return fail(fmt.Errorf("restore/sniff: %w", err))
 	}
+	return in, nil
+}
 
-	fmt.Printf("restore OK: db=%s from=%s tool=psql\n", dbName, fromPath)
-	return nil
+// openRestoreSource opens the backup file, or stdin for "-". size is 0 when unknown
+// (pipes), which disables free-space checks and progress percentages.
+func openRestoreSource(from string) (*os.File, int64, error) {
+	f := os.Stdin
+	if from != "-" {
+		var err error
+		if f, err = os.Open(from); err != nil {
+			return nil, 0, fmt.Errorf("restore/open: %w", err)
+		}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, fmt.Errorf("restore/stat: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return f, 0, nil
+	}
+	return f, fi.Size(), nil
 }
 
-func validateRestoreToolAvailability(decodedKind string) error {
-	switch decodedKind {
-	case "sql":
-		if _, err := execLookPath("psql"); err != nil {
-			return fmt.Errorf("psql not found in PATH: %w", err)
+func pickDatabase(cfg *config.Config, name string) (*config.DatabaseConfig, error) {
+	if name == "" {
+		if len(cfg.Databases) == 0 {
+			return nil, fmt.Errorf("no databases configured")
 		}
-	case "pgdmp":
-		if _, err := execLookPath("pg_restore"); err != nil {
-			return fmt.Errorf("pg_restore not found in PATH: %w", err)
+		return &cfg.Databases[0], nil
+	}
+	for i := range cfg.Databases {
+		if cfg.Databases[i].Name == name {
+			return &cfg.Databases[i], nil
 		}
+	}
+	return nil, fmt.Errorf("db %q not found in config", name)
+}
+
+// decodeStream reverses the backup pipeline (decrypt -> gunzip) from the stream's
+// own headers rather than config. Errors are prefixed with the failing stage.
+func decodeStream(raw *bufio.Reader, rawKind string, db *config.DatabaseConfig, cs *backup.CloseStack) (*bufio.Reader, string, error) {
+	stream := io.Reader(raw)
+	switch rawKind {
+	case "enc":
+		if db.Backup.Encryption.Password == "" {
+			return nil, "", fmt.Errorf("decrypt: encryption password is empty (db=%s)", db.Name)
+		}
+		stream = decryptReader(stream, db.Backup.Encryption.Password, cs)
+	case "gzip":
+		stream = gunzipReader(stream, cs)
+	case "pgdmp", "sqlite", "mongo", "rdb", "tar", "zip", "unknown":
+		// no transform
 	default:
-		return fmt.Errorf("unsupported decoded stream kind %q", decodedKind)
+		return nil, "", fmt.Errorf("sniff: unsupported raw stream kind %q", rawKind)
 	}
-	return nil
+
+	br := bufio.NewReader(stream)
+	innerKind := rawKind
+	if rawKind == "enc" {
+		var err error
+		innerKind, err = sniffLeadingKind(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("sniff: %w", err)
+		}
+		if innerKind == "gzip" {
+			br = bufio.NewReader(gunzipReader(br, cs))
+		}
+	}
+	return br, innerKind, nil
 }
 
-func sniffRawKind(f *os.File) (string, error) {
-	var hdr [8]byte
-	n, err := io.ReadFull(f, hdr[:])
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+func sniffRawKind(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(len(encMagic))
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
 		return "", err
 	}
-	if _, seekErr := f.Seek(0, io.SeekStart); seekErr != nil {
-		return "", seekErr
-	}
-	b := hdr[:n]
 	switch {
 	case len(b) >= len(encMagic) && bytes.Equal(b[:len(encMagic)], encMagic):
 		return "enc", nil
@@ -331,19 +510,30 @@ func sniffRawKind(f *os.File) (string, error) {
 		return "gzip", nil
 	case len(b) >= len(pgdmpMagic) && bytes.Equal(b[:len(pgdmpMagic)], pgdmpMagic):
 		return "pgdmp", nil
+	}
+	if kind := sniffFileMagic(r); kind != "" {
+		return kind, nil
+	}
+	switch kind, err := sniffContainerKind(r); {
+	case err != nil:
+		return "", err
+	case kind == "dirzip":
+		return "zip", nil
+	case kind != "":
+		return "tar", nil
 	default:
 		return "unknown", nil
 	}
 }
 
-func expectedRawKind(compression bool, encryption bool) string {
+func expectedRawKind(plain string, compression bool, encryption bool) string {
 	if encryption {
 		return "enc"
 	}
 	if compression {
 		return "gzip"
 	}
-	return "pgdmp"
+	return plain
 }
 
 func sniffDecodedKind(r *bufio.Reader) (string, error) {
@@ -354,6 +544,12 @@ func sniffDecodedKind(r *bufio.Reader) (string, error) {
 	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
 		return "", fmt.Errorf("unable to read decoded stream header: %w", err)
 	}
+	if kind := sniffFileMagic(r); kind != "" {
+		return kind, nil
+	}
+	if kind, err := sniffContainerKind(r); err != nil || kind != "" {
+		return kind, err
+	}
 
 	probe, err := r.Peek(256)
 	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
@@ -381,16 +577,37 @@ func sniffLeadingKind(r *bufio.Reader) (string, error) {
 		return "gzip", nil
 	case len(h) >= len(pgdmpMagic) && bytes.Equal(h[:len(pgdmpMagic)], pgdmpMagic):
 		return "pgdmp", nil
-	default:
-		if looksLikeSQL(string(h)) {
-			return "sql", nil
+	}
+	if kind := sniffFileMagic(r); kind != "" {
+		return kind, nil
+	}
+	switch kind, err := sniffContainerKind(r); {
+	case err != nil:
+		return "", err
+	case kind == "dirzip":
+		return "zip", nil
+	case kind != "":
+		return "tar", nil
+	}
+	if looksLikeSQL(string(h)) {
+		return "sql", nil
+	}
+	return "unknown", nil
+}
+
+// sniffFileMagic returns the fileMagics kind r starts with, or "".
+func sniffFileMagic(r *bufio.Reader) string {
+	for _, m := range fileMagics {
+		if b, _ := r.Peek(len(m.magic)); bytes.Equal(b, m.magic) {
+			return m.kind
 		}
-		return "unknown", nil
 	}
+	return ""
 }
 
 func looksLikeSQL(s string) bool {
-	trimmed := strings.TrimSpace(s)
+	// Dumps written by other tools may start with a UTF-8 byte order mark.
+	trimmed := strings.TrimSpace(strings.TrimPrefix(s, "\ufeff"))
 	if trimmed == "" {
 		return false
 	}
@@ -408,7 +625,13 @@ func looksLikeSQL(s string) bool {
 		"ALTER ",
 		"DO ",
 		"SELECT ",
-		"\\CONNECT ",
+		"DROP ",
+		"GRANT ",
+		"REVOKE ",
+		"COMMENT ",
+		"START TRANSACTION",
+		// psql meta-commands such as \connect, \restrict or \encoding
+		"\\",
 	}
 	for _, p := range prefixes {
 		if strings.HasPrefix(upper, p) {
@@ -418,8 +641,8 @@ func looksLikeSQL(s string) bool {
 	return false
 }
 
-func expectedBackupExt(compression bool, encryption bool) string {
-	ext := ".dump"
+func expectedBackupExt(base string, compression bool, encryption bool) string {
+	ext := base
 	if compression {
 		ext += ".gz"
 	}
@@ -430,16 +653,18 @@ func expectedBackupExt(compression bool, encryption bool) string {
 }
 
 func backupSuffix(name string) string {
-	switch {
-	case strings.HasSuffix(name, ".dump.gz.enc"):
-		return ".dump.gz.enc"
-	case strings.HasSuffix(name, ".dump.enc"):
-		return ".dump.enc"
-	case strings.HasSuffix(name, ".dump.gz"):
-		return ".dump.gz"
-	case strings.HasSuffix(name, ".dump"):
-		return ".dump"
-	default:
-		return "<unknown>"
+	suffix := ""
+	for _, ext := range []string{".enc", ".gz"} {
+		if strings.HasSuffix(name, ext) {
+			name = strings.TrimSuffix(name, ext)
+			suffix = ext + suffix
+		}
+	}
+	for _, dbType := range backup.Types() {
+		engine, _ := backup.Lookup(dbType)
+		if engine.Ext != "" && strings.HasSuffix(name, engine.Ext) {
+			return engine.Ext + suffix
+		}
 	}
+	return "<unknown>"
 }
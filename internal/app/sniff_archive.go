@@ -0,0 +1,56 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	tarMagic = []byte("ustar") // at offset 257 of the first header block
+	zipMagic = []byte("PK\x03\x04")
+)
+
+const (
+	tarMagicOffset = 257
+	tarBlockSize   = 512
+
+	// Byte 10 of a PGDMP header is the archive format: magic(5) + version(3) + int/off sizes(2).
+	pgdmpFormatOffset = 10
+	pgdmpFormatTar    = 3
+)
+
+// sniffContainerKind recognizes tar and zip containers: "pgtar" is a pg_dump -Ft
+// archive (first member toc.dat in tar format), "basetar" a pg_basebackup tar
+// (first member backup_label), "dirtar"/"dirzip" are assumed to hold a
+// directory-format dump. Returns "" for anything else.
+func sniffContainerKind(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(2 * tarBlockSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("unable to inspect decoded stream: %w", err)
+	}
+	if bytes.HasPrefix(b, zipMagic) {
+		return "dirzip", nil
+	}
+	if len(b) < tarMagicOffset+len(tarMagic) || !bytes.Equal(b[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic) {
+		return "", nil
+	}
+
+	name := string(b[:100])
+	if i := strings.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	toc := b[tarBlockSize:]
+	if path.Clean(name) == "toc.dat" && len(toc) > pgdmpFormatOffset &&
+		bytes.HasPrefix(toc, pgdmpMagic) && toc[pgdmpFormatOffset] == pgdmpFormatTar {
+		return "pgtar", nil
+	}
+	if path.Clean(name) == "backup_label" {
+		return "basetar", nil
+	}
+	return "dirtar", nil
+}
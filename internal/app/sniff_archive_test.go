@@ -0,0 +1,89 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// pgdmpHeader returns a PGDMP header with the given archive format byte.
+func pgdmpHeader(format byte) []byte {
+	return append([]byte("PGDMP"), 1, 14, 0, 4, 8, format, 0, 0)
+}
+
+func tarBytes(t *testing.T, files map[string][]byte, order ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		if strings.HasSuffix(name, "/") {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniffDecodedKindArchives(t *testing.T) {
+	pgTar := tarBytes(t, map[string][]byte{"toc.dat": pgdmpHeader(pgdmpFormatTar), "3001.dat": []byte("x")}, "toc.dat", "3001.dat")
+	dirTar := tarBytes(t, map[string][]byte{"dump/toc.dat": pgdmpHeader(5)}, "dump/", "dump/toc.dat")
+	baseTar := tarBytes(t, map[string][]byte{"backup_label": []byte("START WAL LOCATION: 0/2000028\n")}, "backup_label", "base/")
+	dirZip := zipBytes(t, map[string][]byte{"dump/toc.dat": pgdmpHeader(5)})
+
+	cases := map[string][]byte{
+		"pgtar":   pgTar,
+		"dirtar":  dirTar,
+		"basetar": baseTar,
+		"dirzip":  dirZip,
+		"pgdmp":   pgdmpHeader(1),
+		"sql":     []byte("\ufeff\\restrict abc\nSET statement_timeout = 0;\n"),
+	}
+	for want, data := range cases {
+		got, err := sniffDecodedKind(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("%s: %v", want, err)
+		}
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+
+	raw, err := sniffRawKind(bufio.NewReader(bytes.NewReader(pgTar)))
+	if err != nil || raw != "tar" {
+		t.Fatalf("expected raw kind tar, got %q (%v)", raw, err)
+	}
+}
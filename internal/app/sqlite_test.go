@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func sqliteExec(t *testing.T, path, sql string) string {
+	t.Helper()
+	out, err := exec.Command("sqlite3", path, sql).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 %s: %v: %s", sql, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestSQLiteBackupAndRestoreReplacesFile(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	sqliteExec(t, dbPath, "PRAGMA journal_mode=WAL; CREATE TABLE t (id int); INSERT INTO t VALUES (1);")
+
+	cfg := &config.Config{
+		Version: 1,
+		Storage: []config.StorageConfig{{Name: "local", Type: "local", Local: &config.LocalConfig{Path: t.TempDir()}}},
+		Databases: []config.DatabaseConfig{{
+			Name:   "app",
+			Type:   "sqlite",
+			SQLite: &config.SQLiteConfig{Path: dbPath},
+			Backup: config.BackupConfig{
+				Storage:     "local",
+				Compression: true,
+				Encryption:  config.EncryptionConfig{Enabled: true, Password: "secret"},
+			},
+		}},
+	}
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !strings.HasSuffix(results[0].Key, ".sqlite.gz.enc") {
+		t.Fatalf("expected .sqlite.gz.enc key, got %s", results[0].Key)
+	}
+
+	sqliteExec(t, dbPath, "INSERT INTO t VALUES (2); PRAGMA wal_checkpoint(TRUNCATE);")
+
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "app", FromPath: results[0].Dest})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := sqliteExec(t, dbPath, "SELECT group_concat(id) FROM t;"); got != "1" {
+		t.Fatalf("expected restored rows 1, got %q", got)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(dbPath), ".app.db.restore-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("expected no temp files, got %v", leftovers)
+	}
+
+	if err := os.WriteFile(dbPath+"-wal", []byte("frames"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = RunRestore(context.Background(), cfg, RestoreOptions{DBName: "app", FromPath: results[0].Dest})
+	if err == nil || !strings.Contains(err.Error(), "-wal is not empty") {
+		t.Fatalf("expected refusal with pending WAL, got %v", err)
+	}
+}
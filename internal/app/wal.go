@@ -0,0 +1,270 @@
+package app
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/dev-tams/backupkit/internal/backup"
+	"github.com/dev-tams/backupkit/internal/config"
+	"github.com/dev-tams/backupkit/internal/storage"
+	"github.com/dev-tams/backupkit/internal/storage/prunable"
+	"github.com/dev-tams/backupkit/internal/storage/readable"
+)
+
+// walDir holds a database's archived WAL files, next to its base backups.
+const walDir = "wal"
+
+// walNameRe matches the file names Postgres archives: segments (with .partial
+// after a promotion), backup history files and timeline history files.
+var walNameRe = regexp.MustCompile(`^([0-9A-F]{24}(\.partial|\.[0-9A-F]{8}\.backup)?|[0-9A-F]{8}\.history)$`)
+
+// ErrWALNotFound is returned by RunWALFetch when the archive has no such file;
+// Postgres expects that during recovery and stops replaying.
+var ErrWALNotFound = errors.New("wal-fetch: not in archive")
+
+// walTarget resolves the database and its storage for wal-push and wal-fetch.
+func walTarget(ctx context.Context, cfg *config.Config, dbName, name string) (*config.DatabaseConfig, storage.Storage, string, error) {
+	if !walNameRe.MatchString(name) {
+		return nil, nil, "", fmt.Errorf("wal: %q is not a WAL file name", name)
+	}
+	db, err := pickDatabase(cfg, dbName)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if db.Postgres == nil || !db.Postgres.WALArchive {
+		return nil, nil, "", fmt.Errorf("wal: db %s does not set postgres.wal_archive", db.Name)
+	}
+	stores, err := storage.FromConfigByNames(ctx, cfg, map[string]struct{}{db.Backup.Storage: {}})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("wal/storage: %w", err)
+	}
+	st, ok := stores[db.Backup.Storage]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("wal/storage: storage %q not found", db.Backup.Storage)
+	}
+	ext := expectedBackupExt("", db.Backup.Compression, db.Backup.Encryption.Enabled)
+	return db, st, path.Join(db.Name, walDir, name+ext), nil
+}
+
+// RunWALPush archives one WAL file; use it as archive_command ('... %p %f').
+// A file that is already archived with the same content succeeds, so Postgres
+// can retry after a crash; different content fails instead of overwriting.
+func RunWALPush(ctx context.Context, cfg *config.Config, dbName, src, name string, verbose bool) error {
+	db, st, key, err := walTarget(ctx, cfg, dbName, name)
+	if err != nil {
+		return err
+	}
+	local, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("wal-push: %w", err)
+	}
+
+	if rd, ok := st.(readable.Readable); ok {
+		existing, err := readWAL(ctx, db, rd, key)
+		switch {
+		case err == nil && bytes.Equal(existing, local):
+			if verbose {
+				fmt.Fprintf(os.Stderr, "wal-push: db=%s file=%s already archived\n", db.Name, name)
+			}
+			return nil
+		case err == nil:
+			return fmt.Errorf("wal-push: %s is already archived with different content; refusing to overwrite", key)
+		case !errors.Is(err, ErrWALNotFound):
+			return err
+		}
+	}
+
+	w, dest, err := st.OpenWriter(ctx, key)
+	if err != nil {
+		return fmt.Errorf("wal-push: open storage writer: %w", err)
+	}
+	var cs backup.CloseStack
+	_, copyErr := io.Copy(w, encodeBackupStream(bytes.NewReader(local), *db, &cs))
+	cs.CloseAll()
+	closeErr := w.Close()
+	if copyErr != nil {
+		return fmt.Errorf("wal-push: write: %w", copyErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("wal-push: finalize storage write: %w", closeErr)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "wal-push OK: db=%s file=%s bytes=%d dest=%s\n", db.Name, name, len(local), dest)
+	}
+	return nil
+}
+
+// RunWALFetch restores one archived WAL file to dest; use it as restore_command
+// ('... %f %p'). It returns ErrWALNotFound for files that were never archived.
+func RunWALFetch(ctx context.Context, cfg *config.Config, dbName, name, dest string, verbose bool) error {
+	db, st, key, err := walTarget(ctx, cfg, dbName, name)
+	if err != nil {
+		return err
+	}
+	rd, ok := st.(readable.Readable)
+	if !ok {
+		return fmt.Errorf("wal-fetch: storage %q cannot be read back", st.Name())
+	}
+	data, err := readWAL(ctx, db, rd, key)
+	if err != nil {
+		return err
+	}
+
+	// Postgres may read dest as soon as it exists, so it only appears complete.
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".wal-fetch-*")
+	if err != nil {
+		return fmt.Errorf("wal-fetch: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("wal-fetch: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("wal-fetch: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("wal-fetch: %w", err)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "wal-fetch OK: db=%s file=%s bytes=%d\n", db.Name, name, len(data))
+	}
+	return nil
+}
+
+// readWAL reads and decodes an archived WAL file.
+func readWAL(ctx context.Context, db *config.DatabaseConfig, rd readable.Readable, key string) ([]byte, error) {
+	rc, _, err := rd.OpenReader(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrWALNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wal/open: %w", err)
+	}
+	defer rc.Close()
+
+	raw := bufio.NewReader(rc)
+	rawKind, err := sniffRawKind(raw)
+	if err != nil {
+		return nil, fmt.Errorf("wal/sniff: %w", err)
+	}
+	var cs backup.CloseStack
+	defer cs.CloseAll()
+	br, _, err := decodeStream(raw, rawKind, db, &cs)
+	if err != nil {
+		return nil, fmt.Errorf("wal/%w", err)
+	}
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("wal/read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// backupLabelStartRe finds the first WAL segment a base backup needs.
+var backupLabelStartRe = regexp.MustCompile(`START WAL LOCATION: \S+ \(file ([0-9A-F]{24})\)`)
+
+// pruneWAL deletes archived WAL older than the start segment of baseKey, the
+// oldest base backup retention kept. Timeline history files are always kept.
+// If the start segment cannot be read, nothing is deleted.
+func pruneWAL(ctx context.Context, db config.DatabaseConfig, st storage.Storage, baseKey string, verbose bool) error {
+	pr, ok := st.(prunable.Prunable)
+	rd, readOK := st.(readable.Readable)
+	if !ok || !readOK {
+		fmt.Printf("warning: retention: db=%s wal kept: storage %s cannot list, delete or read backups\n", db.Name, st.Name())
+		return nil
+	}
+	start, err := baseBackupStartSegment(ctx, &db, rd, baseKey)
+	if err != nil {
+		fmt.Printf("warning: retention: db=%s wal kept: %v\n", db.Name, err)
+		return nil
+	}
+
+	objects, err := pr.List(ctx, path.Join(db.Name, walDir))
+	if err != nil {
+		return fmt.Errorf("retention list: %w", err)
+	}
+	deleted := 0
+	for _, o := range objects {
+		name := path.Base(o.Key)
+		if len(name) < 24 || !walNameRe.MatchString(name[:24]) {
+			continue // timeline history files and anything unknown
+		}
+		// Compare log and segment numbers; the timeline prefix does not order WAL.
+		if name[8:24] >= start[8:24] {
+			continue
+		}
+		if err := pr.Delete(ctx, o.Key); err != nil {
+			return fmt.Errorf("retention delete: %w", err)
+		}
+		deleted++
+	}
+	if verbose {
+		fmt.Printf("retention: db=%s/%s storage=%s before=%s deleted=%d\n", db.Name, walDir, st.Name(), start, deleted)
+	}
+	return nil
+}
+
+// baseBackupStartSegment reads the backup_label at the head of a physical
+// backup; only the first tar member is decoded.
+func baseBackupStartSegment(ctx context.Context, db *config.DatabaseConfig, rd readable.Readable, key string) (string, error) {
+	rc, _, err := rd.OpenReader(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	raw := bufio.NewReader(rc)
+	rawKind, err := sniffRawKind(raw)
+	if err != nil {
+		return "", err
+	}
+	var cs backup.CloseStack
+	defer cs.CloseAll()
+	br, _, err := decodeStream(raw, rawKind, db, &cs)
+	if err != nil {
+		return "", err
+	}
+	tr := tar.NewReader(br)
+	hdr, err := tr.Next()
+	if err != nil || path.Clean(hdr.Name) != "backup_label" {
+		return "", fmt.Errorf("%s is not a pg_basebackup tar", key)
+	}
+	label, err := io.ReadAll(io.LimitReader(tr, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("read backup_label of %s: %w", key, err)
+	}
+	m := backupLabelStartRe.FindSubmatch(label)
+	if m == nil {
+		return "", fmt.Errorf("backup_label of %s has no START WAL LOCATION", key)
+	}
+	return string(m[1]), nil
+}
+
+// walFetchArgv is the restore_command of a physical restore: this binary's
+// wal-fetch for db, with an absolute config path so it works from any cwd.
+func walFetchArgv(cfgPath, dbName string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("restore: locate backupkit binary for restore_command: %w", err)
+	}
+	argv := []string{exe, "wal-fetch"}
+	if cfgPath != "" {
+		abs, err := filepath.Abs(cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+		argv = append(argv, "-c", abs)
+	}
+	return append(argv, "--db", dbName), nil
+}
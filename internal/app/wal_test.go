@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func walTestConfig(store string) *config.Config {
+	cfg := physicalTestConfig(store)
+	cfg.Databases[0].Postgres.WALArchive = true
+	return cfg
+}
+
+func writeSegment(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestWALPushAndFetch(t *testing.T) {
+	store := t.TempDir()
+	cfg := walTestConfig(store)
+	ctx := context.Background()
+	pgwal := t.TempDir()
+	seg := "000000010000000000000003"
+	src := writeSegment(t, pgwal, seg, "wal-bytes")
+
+	if err := RunWALPush(ctx, cfg, "cluster", src, seg, false); err != nil {
+		t.Fatalf("wal-push: %v", err)
+	}
+	stored := filepath.Join(store, "cluster", "wal", seg+".gz.enc")
+	if _, err := os.Stat(stored); err != nil {
+		t.Fatalf("expected encoded segment in storage: %v", err)
+	}
+	// Postgres retries archive_command after a crash; the same content succeeds.
+	if err := RunWALPush(ctx, cfg, "cluster", src, seg, false); err != nil {
+		t.Fatalf("repeated wal-push: %v", err)
+	}
+	other := writeSegment(t, t.TempDir(), seg, "different")
+	if err := RunWALPush(ctx, cfg, "cluster", other, seg, false); err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Fatalf("expected overwrite refusal, got %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "RECOVERYXLOG")
+	if err := RunWALFetch(ctx, cfg, "cluster", seg, dest, false); err != nil {
+		t.Fatalf("wal-fetch: %v", err)
+	}
+	if got := readFile(t, dest); got != "wal-bytes" {
+		t.Fatalf("unexpected fetched segment %q", got)
+	}
+	if err := RunWALFetch(ctx, cfg, "cluster", "000000010000000000000004", dest, false); !errors.Is(err, ErrWALNotFound) {
+		t.Fatalf("expected ErrWALNotFound, got %v", err)
+	}
+	if err := RunWALFetch(ctx, cfg, "cluster", "../../etc/passwd", dest, false); err == nil || !strings.Contains(err.Error(), "not a WAL file name") {
+		t.Fatalf("expected WAL name rejection, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres.WALArchive = false
+	if err := RunWALPush(ctx, cfg, "cluster", src, seg, false); err == nil || !strings.Contains(err.Error(), "wal_archive") {
+		t.Fatalf("expected wal_archive requirement, got %v", err)
+	}
+}
+
+func TestRetentionPrunesWALBeforeOldestBaseBackup(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	store := t.TempDir()
+	cfg := walTestConfig(store)
+	cfg.Databases[0].Retention = config.RetentionConfig{KeepDaily: 1}
+	ctx := context.Background()
+
+	pgwal := t.TempDir()
+	for _, name := range []string{
+		"000000010000000000000001",
+		"000000010000000000000002",
+		"000000010000000000000003",
+		"000000010000000000000003.00000028.backup",
+		"000000010000000000000004",
+		"00000002.history",
+	} {
+		if err := RunWALPush(ctx, cfg, "cluster", writeSegment(t, pgwal, name, name), name, false); err != nil {
+			t.Fatalf("wal-push %s: %v", name, err)
+		}
+	}
+
+	for _, start := range []string{"000000010000000000000001", "000000010000000000000003"} {
+		base := tarBytes(t, map[string][]byte{
+			"backup_label": []byte("START WAL LOCATION: 0/3000028 (file " + start + ")\nCHECKPOINT LOCATION: 0/3000060\n"),
+		}, "backup_label", "base/")
+		if err := os.WriteFile(filepath.Join(work, "base.tar"), base, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		fakeTool(t, bin, "pg_basebackup", `cat `+work+`/base.tar
+`)
+		if _, err := RunBackupWithResults(ctx, cfg, false); err != nil {
+			t.Fatalf("backup: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(store, "cluster", "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, strings.TrimSuffix(e.Name(), ".gz.enc"))
+	}
+	sort.Strings(got)
+	want := []string{
+		"000000010000000000000003",
+		"000000010000000000000003.00000028.backup",
+		"000000010000000000000004",
+		"00000002.history",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected WAL after retention:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestPhysicalRestoreWithWALArchiveWritesRestoreCommand(t *testing.T) {
+	bin := withFakeBin(t)
+	work := t.TempDir()
+	base := tarBytes(t, map[string][]byte{
+		"backup_label": []byte("START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n"),
+	}, "backup_label", "base/")
+	if err := os.WriteFile(filepath.Join(work, "base.tar"), base, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fakeTool(t, bin, "pg_basebackup", `cat `+work+`/base.tar
+`)
+
+	cfg := walTestConfig(t.TempDir())
+	results, err := RunBackupWithResults(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	datadir := filepath.Join(t.TempDir(), "pgdata")
+	opts := RestoreOptions{
+		DBName:     "cluster",
+		FromPath:   results[0].Dest,
+		OutPath:    datadir,
+		TargetTime: "2026-03-01T15:30:00+01:00",
+		ConfigPath: "backupkit.yaml",
+	}
+	if err := RunRestore(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	auto := readFile(t, filepath.Join(datadir, "postgresql.auto.conf"))
+	if !strings.Contains(auto, "wal-fetch -c ") || !strings.Contains(auto, "backupkit.yaml --db cluster %f %p'") {
+		t.Fatalf("expected wal-fetch restore_command, got:\n%s", auto)
+	}
+	if !strings.Contains(auto, "recovery_target_time = '2026-03-01 14:30:00+00'") || strings.Contains(auto, "'immediate'") {
+		t.Fatalf("expected recovery_target_time in UTC, got:\n%s", auto)
+	}
+
+	cfg.Databases[0].Postgres.WALArchive = false
+	opts.OutPath = filepath.Join(t.TempDir(), "pgdata")
+	if err := RunRestore(context.Background(), cfg, opts); err == nil || !strings.Contains(err.Error(), "needs postgres.wal_archive") {
+		t.Fatalf("expected --target-time to need wal_archive, got %v", err)
+	}
+}
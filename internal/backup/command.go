@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("command", Engine{
+		Backupper: CommandBackupper{},
+		Restorer:  CommandRestorer{},
+		Ext:       ".bin",
+		// The output format is whatever the command writes, so it is not checked.
+		Header: "",
+	})
+}
+
+// CommandBackupper runs a configured argv and streams its stdout, for tools
+// backupkit has no dedicated engine for.
+type CommandBackupper struct{}
+
+func (backup CommandBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	c := commandOptions(cfg)
+	if len(c.Backup) == 0 {
+		return nil, fmt.Errorf("command: no backup argv configured")
+	}
+	if _, err := execLookPath(c.Backup[0]); err != nil {
+		return nil, fmt.Errorf("%s not found in PATH: %w", c.Backup[0], err)
+	}
+
+	cmd := exec.CommandContext(ctx, c.Backup[0], c.Backup[1:]...)
+	cmd.Env = append(os.Environ(), c.Env...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("%s failed: %w : %s", c.Backup[0], err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+// CommandRestorer feeds the decoded stream to the configured restore argv.
+type CommandRestorer struct{}
+
+func (CommandRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for command sources", strings.Join(set, ", "))
+	}
+	if req.Options.Clean {
+		return fmt.Errorf("restore: --clean not supported for command sources; the restore command decides what is replaced")
+	}
+	c := commandOptions(req.DB)
+	if len(c.Restore) == 0 {
+		return fmt.Errorf("restore: db %s has no command.restore argv; use decode to get the backup stream", req.DB.Name)
+	}
+	if _, err := execLookPath(c.Restore[0]); err != nil {
+		return fmt.Errorf("%s not found in PATH: %w", c.Restore[0], err)
+	}
+	return nil
+}
+
+func (CommandRestorer) Target(db config.DatabaseConfig) string {
+	if c := commandOptions(db); len(c.Restore) > 0 {
+		return "command:" + c.Restore[0]
+	}
+	return "command"
+}
+
+func (CommandRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	c := commandOptions(req.DB)
+	fmt.Fprintf(w, "  command:  %s\n", shellJoin(c.Restore))
+	if len(c.Env) > 0 {
+		// Values often hold credentials; only the names are shown.
+		names := make([]string, 0, len(c.Env))
+		for _, kv := range c.Env {
+			k, _, _ := strings.Cut(kv, "=")
+			names = append(names, k)
+		}
+		fmt.Fprintf(w, "  env:      %s\n", strings.Join(names, " "))
+	}
+	return nil
+}
+
+func (CommandRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	c := commandOptions(req.DB)
+	var cs CloseStack
+	return runStdinRestore(ctx, c.Restore[0], c.Restore[1:], append(os.Environ(), c.Env...), req.Stream, &cs, req)
+}
+
+func commandOptions(cfg config.DatabaseConfig) config.CommandConfig {
+	if cfg.Command == nil {
+		return config.CommandConfig{}
+	}
+	return *cfg.Command
+}
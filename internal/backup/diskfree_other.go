@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package backup
+
+// diskFree is not implemented on this platform; callers skip the free-space check.
+func diskFree(string) (int64, bool) {
+	return 0, false
+}
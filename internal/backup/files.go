@@ -0,0 +1,316 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("files", Engine{
+		Backupper: FilesBackupper{},
+		Restorer:  FilesRestorer{},
+		Ext:       ".tar",
+		Header:    "tar",
+	})
+}
+
+// ignoreFileName lists exclude patterns for the directory it is in and below.
+const ignoreFileName = ".backupkitignore"
+
+type FilesBackupper struct{}
+
+// Backup streams a tar of the configured directories. Each directory is stored
+// under its absolute path without the leading slash, as tar does, so several
+// sources never collide.
+func (backup FilesBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	opts := config.FilesConfig{}
+	if cfg.Files != nil {
+		opts = *cfg.Files
+	}
+	roots := make([]string, 0, len(opts.Paths))
+	for _, p := range opts.Paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("files: %w", err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("files: %w", err)
+		}
+		roots = append(roots, abs)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriterSize(pw, 256*1024)
+		w := &filesWalker{
+			ctx:     ctx,
+			tw:      tar.NewWriter(bw),
+			opts:    opts,
+			warn:    os.Stderr,
+			visited: map[string]bool{},
+		}
+		err := w.writeAll(roots)
+		if err == nil {
+			err = w.tw.Close()
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("files backup failed: %w", err))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+type filesWalker struct {
+	ctx  context.Context
+	tw   *tar.Writer
+	opts config.FilesConfig
+	warn io.Writer
+	// visited holds resolved directories when following symlinks, to stop loops.
+	visited map[string]bool
+}
+
+// ignoreScope is the .backupkitignore of the directory at rel.
+type ignoreScope struct {
+	rel      string
+	patterns []string
+}
+
+func (w *filesWalker) writeAll(roots []string) error {
+	for _, root := range roots {
+		// A configured path is always archived by content, even if it is a symlink.
+		info, err := os.Stat(root)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(root, filepath.VolumeName(root))), "/")
+		if name == "" {
+			name = "root"
+		}
+		rel := ""
+		if !info.IsDir() {
+			rel = filepath.Base(root)
+		}
+		if err := w.add(root, name, rel, info, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add writes the entry at abs as name; rel is its path below the source root
+// and is what include/exclude patterns are matched against.
+func (w *filesWalker) add(abs, name, rel string, info fs.FileInfo, scopes []ignoreScope) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		switch w.opts.Symlinks {
+		case "skip":
+			return nil
+		case "follow":
+			target, err := os.Stat(abs)
+			if err != nil {
+				fmt.Fprintf(w.warn, "warning: files: skipping dangling symlink %s\n", abs)
+				return nil
+			}
+			info = target
+		default:
+			if !w.included(rel) {
+				return nil
+			}
+			link, err := os.Readlink(abs)
+			if err != nil {
+				return err
+			}
+			return w.writeHeader(info, name, link)
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		if w.opts.Symlinks == "follow" {
+			real, err := filepath.EvalSymlinks(abs)
+			if err != nil {
+				return err
+			}
+			if w.visited[real] {
+				fmt.Fprintf(w.warn, "warning: files: skipping %s, already archived via another symlink\n", abs)
+				return nil
+			}
+			w.visited[real] = true
+		}
+		if err := w.writeHeader(info, name+"/", ""); err != nil {
+			return err
+		}
+		return w.walkDir(abs, name, rel, scopes)
+	case info.Mode().IsRegular():
+		if !w.included(rel) {
+			return nil
+		}
+		return w.writeFile(abs, name, info)
+	default:
+		fmt.Fprintf(w.warn, "warning: files: skipping special file %s (%s)\n", abs, info.Mode().Type())
+		return nil
+	}
+}
+
+func (w *filesWalker) walkDir(abs, name, rel string, scopes []ignoreScope) error {
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return err
+	}
+	patterns, err := readIgnoreFile(filepath.Join(abs, ignoreFileName))
+	if err != nil {
+		return err
+	}
+	if len(patterns) > 0 {
+		scopes = append(scopes[:len(scopes):len(scopes)], ignoreScope{rel: rel, patterns: patterns})
+	}
+
+	for _, e := range entries {
+		childAbs := filepath.Join(abs, e.Name())
+		childRel := path.Join(rel, e.Name())
+		info, err := os.Lstat(childAbs)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // removed while walking
+		}
+		if err != nil {
+			return err
+		}
+		if w.excluded(childRel, info.IsDir(), scopes) {
+			continue
+		}
+		if err := w.add(childAbs, name+"/"+e.Name(), childRel, info, scopes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *filesWalker) writeHeader(info fs.FileInfo, name, link string) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	return w.tw.WriteHeader(hdr)
+}
+
+func (w *filesWalker) writeFile(abs, name string, info fs.FileInfo) error {
+	f, err := os.Open(abs)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	// The header already promised hdr.Size bytes; a file that grows is cut off.
+	if _, err := io.CopyN(w.tw, f, hdr.Size); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s shrank while it was being archived", abs)
+		}
+		return err
+	}
+	return nil
+}
+
+func (w *filesWalker) included(rel string) bool {
+	if len(w.opts.Include) == 0 {
+		return true
+	}
+	for _, p := range w.opts.Include {
+		if matchFilesPattern(p, rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *filesWalker) excluded(rel string, isDir bool, scopes []ignoreScope) bool {
+	for _, p := range w.opts.Exclude {
+		if matchFilesPattern(p, rel, isDir) {
+			return true
+		}
+	}
+	for _, s := range scopes {
+		sub := rel
+		if s.rel != "" {
+			sub = strings.TrimPrefix(rel, s.rel+"/")
+		}
+		for _, p := range s.patterns {
+			if matchFilesPattern(p, sub, isDir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchFilesPattern matches a glob against a slash-separated relative path.
+// Patterns without a slash match the base name at any depth, a leading slash
+// anchors the pattern and a trailing slash only matches directories.
+func matchFilesPattern(pattern, rel string, isDir bool) bool {
+	if strings.HasSuffix(pattern, "/") {
+		if !isDir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel)
+	return ok
+}
+
+// readIgnoreFile returns the patterns of a .backupkitignore; blank lines and
+// lines starting with # are skipped.
+func readIgnoreFile(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", p, err)
+	}
+	return patterns, nil
+}
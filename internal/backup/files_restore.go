@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// FilesRestorer extracts a files backup into the directory given by --out.
+type FilesRestorer struct{}
+
+func (FilesRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for files sources", strings.Join(set, ", "))
+	}
+	if req.Kind != "dirtar" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a files backup (got %s)", req.Kind)
+	}
+	dir := req.Options.OutPath
+	if dir == "" {
+		return fmt.Errorf("restore: --out <dir> is required for files sources")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("restore: %w", err)
+	}
+	if len(entries) > 0 && !req.Options.Clean {
+		return fmt.Errorf("restore: %s is not empty; rerun with --clean to overwrite files in it", dir)
+	}
+	return nil
+}
+
+func (FilesRestorer) Target(db config.DatabaseConfig) string {
+	if db.Files == nil {
+		return "files"
+	}
+	return "files:" + strings.Join(db.Files.Paths, ",")
+}
+
+func (FilesRestorer) OutputPath(req RestoreRequest) string {
+	return req.Options.OutPath
+}
+
+func (FilesRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	var sum filesSummary
+	tr := tar.NewReader(req.Stream)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("restore/tar: %w", err)
+		}
+		sum.count(hdr)
+	}
+	fmt.Fprintf(w, "  entries:  %s\n", sum)
+	if req.Options.Clean {
+		fmt.Fprintln(w, "  clean:    existing files with the same names are overwritten")
+	}
+	return nil
+}
+
+func (FilesRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	dir := req.Options.OutPath
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("restore/files: %w", err)
+	}
+	sum, err := extractFiles(ctx, req.Stream, dir, req.Options.Clean)
+	if err != nil {
+		return fmt.Errorf("restore/files: %w", err)
+	}
+	fmt.Fprintf(req.Out, "restore OK: db=%s from=%s dir=%s %s\n", req.DB.Name, req.From, dir, sum)
+	return nil
+}
+
+type filesSummary struct {
+	files, dirs, links int
+	bytes              int64
+}
+
+func (s *filesSummary) count(hdr *tar.Header) {
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		s.dirs++
+	case tar.TypeSymlink:
+		s.links++
+	case tar.TypeReg:
+		s.files++
+		s.bytes += hdr.Size
+	}
+}
+
+func (s filesSummary) String() string {
+	return fmt.Sprintf("files=%d bytes=%d dirs=%d symlinks=%d", s.files, s.bytes, s.dirs, s.links)
+}
+
+// extractFiles unpacks a files backup under root. Member names may not escape
+// root and nothing is ever written through a symlink, so a hostile archive
+// cannot touch files outside root. Symlinks themselves are restored as-is.
+func extractFiles(ctx context.Context, src io.Reader, root string, overwrite bool) (filesSummary, error) {
+	var sum filesSummary
+	type dirMeta struct {
+		path  string
+		mode  fs.FileMode
+		mtime time.Time
+	}
+	var dirs []dirMeta
+	asRoot := os.Geteuid() == 0
+
+	tr := tar.NewReader(src)
+	for {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return sum, fmt.Errorf("read tar: %w", err)
+		}
+
+		target, err := safeJoin(root, hdr.Name)
+		if err != nil {
+			return sum, err
+		}
+		if err := checkNoSymlinkParents(root, target); err != nil {
+			return sum, err
+		}
+		existing, statErr := os.Lstat(target)
+		exists := statErr == nil
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if exists && !existing.IsDir() {
+				return sum, fmt.Errorf("extract %s: exists and is not a directory", hdr.Name)
+			}
+			if err := os.MkdirAll(target, 0o700); err != nil {
+				return sum, fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+			// Applied at the end so read-only directories can still be filled.
+			dirs = append(dirs, dirMeta{target, hdr.FileInfo().Mode().Perm(), hdr.ModTime})
+		case tar.TypeReg, tar.TypeSymlink:
+			if exists {
+				if !overwrite || existing.IsDir() {
+					return sum, fmt.Errorf("extract %s: %s already exists", hdr.Name, target)
+				}
+				if err := os.Remove(target); err != nil {
+					return sum, fmt.Errorf("extract %s: %w", hdr.Name, err)
+				}
+			}
+			if hdr.Typeflag == tar.TypeSymlink {
+				err = os.Symlink(hdr.Linkname, target)
+			} else {
+				err = writeRestoredFile(target, tr, hdr)
+			}
+			if err != nil {
+				return sum, fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+		default:
+			return sum, fmt.Errorf("extract %s: unsupported tar entry type %q", hdr.Name, hdr.Typeflag)
+		}
+		if asRoot {
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				return sum, fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+		}
+		sum.count(hdr)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return sum, fmt.Errorf("extract: %w", err)
+		}
+		_ = os.Chtimes(d.path, d.mtime, d.mtime)
+	}
+	return sum, nil
+}
+
+func writeRestoredFile(target string, r io.Reader, hdr *tar.Header) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, hdr.FileInfo().Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+}
+
+// checkNoSymlinkParents fails when a directory between root and target is a
+// symlink, which an earlier archive member could have planted.
+func checkNoSymlinkParents(root, target string) error {
+	rel, err := filepath.Rel(root, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	cur := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		fi, err := os.Lstat(cur)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("extract: %s would be written through symlink %s", target, cur)
+		}
+	}
+	return nil
+}
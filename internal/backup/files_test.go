@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func tarNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFilesBackupAppliesFiltersAndIgnoreFiles(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"app/config.yml":               "a",
+		"app/debug.log":                "b",
+		"app/cache/blob":               "c",
+		"app/uploads/1.png":            "d",
+		"app/uploads/tmp/2.png":        "e",
+		"app/uploads/.backupkitignore": "# scratch space\ntmp/\n",
+	})
+	if err := os.Symlink("config.yml", filepath.Join(src, "app", "current.yml")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DatabaseConfig{Files: &config.FilesConfig{
+		Paths:   []string{filepath.Join(src, "app")},
+		Exclude: []string{"*.log", "/cache/"},
+	}}
+	rc, err := FilesBackupper{}.Backup(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := strings.TrimPrefix(filepath.ToSlash(filepath.Join(src, "app")), "/")
+	var got []string
+	for _, n := range tarNames(t, data) {
+		got = append(got, strings.TrimPrefix(n, prefix))
+	}
+	want := []string{"/", "/config.yml", "/current.yml", "/uploads/", "/uploads/.backupkitignore", "/uploads/1.png"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected entries:\n got %v\nwant %v", got, want)
+	}
+
+	out := t.TempDir()
+	sum, err := extractFiles(context.Background(), bytes.NewReader(data), out, false)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if sum.files != 3 || sum.links != 1 {
+		t.Fatalf("unexpected summary %s", sum)
+	}
+	restored := filepath.Join(out, filepath.FromSlash(prefix))
+	if link, err := os.Readlink(filepath.Join(restored, "current.yml")); err != nil || link != "config.yml" {
+		t.Fatalf("expected preserved symlink, got %q (%v)", link, err)
+	}
+	fi, err := os.Stat(filepath.Join(restored, "config.yml"))
+	if err != nil || fi.Mode().Perm() != 0o640 {
+		t.Fatalf("expected mode 0640, got %v (%v)", fi.Mode().Perm(), err)
+	}
+
+	if _, err := extractFiles(context.Background(), bytes.NewReader(data), out, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected refusal to overwrite, got %v", err)
+	}
+	if _, err := extractFiles(context.Background(), bytes.NewReader(data), out, true); err != nil {
+		t.Fatalf("extract with overwrite: %v", err)
+	}
+}
+
+func TestExtractFilesRejectsEscapes(t *testing.T) {
+	build := func(hdrs ...*tar.Header) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, h := range hdrs {
+			if err := tw.WriteHeader(h); err != nil {
+				t.Fatal(err)
+			}
+			if h.Size > 0 {
+				_, _ = tw.Write(bytes.Repeat([]byte("x"), int(h.Size)))
+			}
+		}
+		_ = tw.Close()
+		return buf.Bytes()
+	}
+
+	outside := t.TempDir()
+	cases := map[string][]byte{
+		"traversal": build(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"through symlink": build(
+			&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			&tar.Header{Name: "link/evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		),
+		"dir over symlink": build(
+			&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			&tar.Header{Name: "link/", Typeflag: tar.TypeDir, Mode: 0o777},
+		),
+	}
+	for name, data := range cases {
+		if _, err := extractFiles(context.Background(), bytes.NewReader(data), t.TempDir(), false); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Fatalf("expected nothing written outside the target, got %d entries", len(entries))
+	}
+}
+
+func TestMatchFilesPattern(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		isDir, want  bool
+	}{
+		{"*.log", "a/b/c.log", false, true},
+		{"/cache/", "cache", true, true},
+		{"/cache/", "a/cache", true, false},
+		{"cache/", "a/cache", true, true},
+		{"cache/", "cache", false, false},
+		{"a/*.png", "a/1.png", false, true},
+		{"a/*.png", "b/a/1.png", false, false},
+	}
+	for _, c := range cases {
+		if got := matchFilesPattern(c.pattern, c.rel, c.isDir); got != c.want {
+			t.Fatalf("matchFilesPattern(%q, %q, %v) = %v, want %v", c.pattern, c.rel, c.isDir, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("mongo", Engine{
+		Backupper: MongoBackupper{},
+		Restorer:  MongoRestorer{},
+		Ext:       ".archive",
+		Header:    "mongo",
+	})
+}
+
+type MongoBackupper struct{}
+
+// Backup streams an uncompressed mongodump archive; compression is left to the
+// pipeline so sniffing and decode behave like the other engines.
+func (backup MongoBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	if _, err := execLookPath("mongodump"); err != nil {
+		return nil, fmt.Errorf("mongodump not found in PATH: %w", err)
+	}
+
+	secrets, cleanup, err := mongoConfigFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "mongodump", mongoDumpArgs(cfg, secrets)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		cleanup()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("mongodump failed: %w : %s", err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+func mongoDumpArgs(cfg config.DatabaseConfig, secrets string) []string {
+	m := mongoOptions(cfg)
+	args := append([]string{"--archive"}, mongoConnArgs(cfg, secrets)...)
+	if m.ReadPreference != "" {
+		args = append(args, "--readPreference="+m.ReadPreference)
+	}
+	// With a URI the database comes from its path.
+	if db := cfg.Connection.Database; db != "" && m.URI == "" {
+		args = append(args, "--db="+db)
+	}
+	return args
+}
+
+// mongoConnArgs are the connection flags shared by mongodump and mongorestore.
+// The password and URI are never put in argv; they go in the --config file.
+func mongoConnArgs(cfg config.DatabaseConfig, secrets string) []string {
+	var args []string
+	m := mongoOptions(cfg)
+	if m.URI == "" {
+		conn := cfg.Connection
+		args = append(args, "--host="+conn.Host, "--port="+strconv.Itoa(conn.Port))
+		if conn.User != "" {
+			args = append(args, "--username="+conn.User)
+		}
+	}
+	if m.AuthDB != "" {
+		args = append(args, "--authenticationDatabase="+m.AuthDB)
+	}
+	if secrets != "" {
+		args = append(args, "--config="+secrets)
+	}
+	return args
+}
+
+// mongoConfigFile writes the URI and password to a private temp file for the
+// tools' --config flag. It returns "" when there is nothing secret to pass.
+func mongoConfigFile(cfg config.DatabaseConfig) (string, func(), error) {
+	values := map[string]string{}
+	if uri := mongoOptions(cfg).URI; uri != "" {
+		values["uri"] = uri
+	}
+	if cfg.Connection.Password != "" {
+		values["password"] = cfg.Connection.Password
+	}
+	if len(values) == 0 {
+		return "", func() {}, nil
+	}
+
+	// JSON is valid YAML and takes care of quoting.
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", nil, fmt.Errorf("mongo/config: %w", err)
+	}
+	f, err := os.CreateTemp("", "backupkit-mongo-*.yaml")
+	if err != nil {
+		return "", nil, fmt.Errorf("mongo/config: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("mongo/config: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+func mongoOptions(cfg config.DatabaseConfig) config.MongoConfig {
+	if cfg.Mongo == nil {
+		return config.MongoConfig{}
+	}
+	return *cfg.Mongo
+}
+
+// MongoRestorer replays a mongodump archive with mongorestore --archive.
+type MongoRestorer struct{}
+
+func (MongoRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for mongo databases", strings.Join(set, ", "))
+	}
+	if req.Kind != "mongo" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a mongodump archive (got %s)", req.Kind)
+	}
+	if _, err := execLookPath("mongorestore"); err != nil {
+		return fmt.Errorf("mongorestore not found in PATH: %w", err)
+	}
+	return nil
+}
+
+func (MongoRestorer) Target(db config.DatabaseConfig) string {
+	if uri := mongoOptions(db).URI; uri != "" {
+		if u, err := url.Parse(uri); err == nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), "REDACTED")
+			}
+			return u.String()
+		}
+		return "mongodb://<unparseable uri>"
+	}
+	u := url.URL{
+		Scheme: "mongodb",
+		Host:   db.Connection.Host + ":" + strconv.Itoa(db.Connection.Port),
+		Path:   "/" + db.Connection.Database,
+	}
+	if db.Connection.User != "" {
+		u.User = url.UserPassword(db.Connection.User, "REDACTED")
+	}
+	return u.String()
+}
+
+func (MongoRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	secrets := ""
+	if mongoOptions(req.DB).URI != "" || req.DB.Connection.Password != "" {
+		secrets = "<temp-file>"
+	}
+	fmt.Fprintf(w, "  command:  mongorestore %s\n", shellJoin(mongoRestoreArgs(req.DB, req.Options, secrets)))
+	if req.Options.Clean {
+		fmt.Fprintln(w, "  clean:    collections in the archive are dropped first (--drop)")
+	}
+	return nil
+}
+
+func (MongoRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	secrets, cleanup, err := mongoConfigFile(req.DB)
+	if err != nil {
+		return fmt.Errorf("restore/%w", err)
+	}
+	defer cleanup()
+
+	var cs CloseStack
+	return runStdinRestore(ctx, "mongorestore", mongoRestoreArgs(req.DB, req.Options, secrets), os.Environ(), req.Stream, &cs, req)
+}
+
+func mongoRestoreArgs(cfg config.DatabaseConfig, opts RestoreOptions, secrets string) []string {
+	args := append([]string{"--archive"}, mongoConnArgs(cfg, secrets)...)
+	if db := cfg.Connection.Database; db != "" {
+		args = append(args, "--nsInclude="+db+".*")
+	}
+	if opts.Clean {
+		args = append(args, "--drop")
+	}
+	return args
+}
@@ -0,0 +1,151 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("mysql", Engine{
+		Backupper: MySQLBackupper{},
+		Restorer:  MySQLRestorer{},
+		Ext:       ".sql",
+		Header:    "unknown",
+	})
+}
+
+type MySQLBackupper struct{}
+
+// Backup streams a mysqldump SQL script for the given MySQL or MariaDB database.
+// --single-transaction gives a consistent InnoDB snapshot without locking tables.
+func (backup MySQLBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	if _, err := execLookPath("mysqldump"); err != nil {
+		return nil, fmt.Errorf("mysqldump not found in PATH: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "mysqldump", mysqlDumpArgs(cfg)...)
+	cmd.Env = mysqlEnv(cfg.Connection)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("mysqldump failed: %w : %s", err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+// mysqlDumpArgs builds the mysqldump argv. The database is dumped without
+// CREATE DATABASE/USE so it can be restored into a differently named schema.
+func mysqlDumpArgs(cfg config.DatabaseConfig) []string {
+	conn := cfg.Connection
+	args := []string{
+		"--host", conn.Host,
+		"--port", strconv.Itoa(conn.Port),
+		"--user", conn.User,
+		"--single-transaction",
+		"--quick",
+	}
+
+	opts := config.MySQLConfig{}
+	if cfg.MySQL != nil {
+		opts = *cfg.MySQL
+	}
+	if opts.Routines {
+		args = append(args, "--routines")
+	}
+	if opts.Events {
+		args = append(args, "--events")
+	}
+	if !opts.TriggersEnabled() {
+		args = append(args, "--skip-triggers")
+	}
+	return append(args, conn.Database)
+}
+
+// mysqlEnv passes the password via MYSQL_PWD so it never shows up in argv.
+func mysqlEnv(conn config.ConnectionConfig) []string {
+	if conn.Password != "" {
+		return append(os.Environ(), "MYSQL_PWD="+conn.Password)
+	}
+	return os.Environ()
+}
+
+// MySQLRestorer replays a mysqldump script with the mysql client.
+type MySQLRestorer struct{}
+
+func (MySQLRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for mysql databases", strings.Join(set, ", "))
+	}
+	if req.Kind != "sql" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a SQL script (got %s); mysql databases restore mysqldump output", req.Kind)
+	}
+	if _, err := execLookPath("mysql"); err != nil {
+		return fmt.Errorf("mysql not found in PATH: %w", err)
+	}
+	return nil
+}
+
+func (MySQLRestorer) Target(db config.DatabaseConfig) string {
+	conn := db.Connection
+	return fmt.Sprintf("mysql://%s:REDACTED@%s:%d/%s", conn.User, conn.Host, conn.Port, conn.Database)
+}
+
+func (MySQLRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	fmt.Fprintf(w, "  command:  mysql %s\n", shellJoin(mysqlArgs(req.DB.Connection)))
+	if req.Options.Clean {
+		fmt.Fprintln(w, "  clean:    ignored; mysqldump scripts drop and recreate each table")
+	}
+	return nil
+}
+
+func (MySQLRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	if req.Options.Clean {
+		fmt.Fprintln(os.Stderr, "warning: --clean is ignored for mysql; mysqldump scripts already drop and recreate each table")
+	}
+	var cs CloseStack
+	conn := req.DB.Connection
+	return runStdinRestore(ctx, "mysql", mysqlArgs(conn), mysqlEnv(conn), req.Stream, &cs, req)
+}
+
+// postgresOnlyFlags lists the set restore options that only apply to pg_restore.
+func postgresOnlyFlags(o RestoreOptions) []string {
+	var set []string
+	if o.selective() {
+		set = append(set, "--schema/--table/--schema-only/--data-only/--list/--use-list")
+	}
+	if o.Jobs > 1 {
+		set = append(set, "--jobs")
+	}
+	if o.NoOwner || o.NoPrivileges || o.Role != "" || len(o.RoleMap) > 0 {
+		set = append(set, "--no-owner/--no-privileges/--role/--role-map")
+	}
+	return set
+}
+
+// mysqlArgs builds the mysql client argv for replaying a script from stdin.
+func mysqlArgs(conn config.ConnectionConfig) []string {
+	return []string{
+		"--host", conn.Host,
+		"--port", strconv.Itoa(conn.Port),
+		"--user", conn.User,
+		conn.Database,
+	}
+}
@@ -0,0 +1,41 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestMySQLRestorerCheckRejectsPostgresOnlyFlags(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+	execLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	err := MySQLRestorer{}.Check(context.Background(), RestoreRequest{Kind: "sql", Options: RestoreOptions{Tables: []string{"t"}, Jobs: 2}})
+	if err == nil || !strings.Contains(err.Error(), "--jobs not supported for mysql") {
+		t.Fatalf("expected mysql flag rejection, got %v", err)
+	}
+
+	err = MySQLRestorer{}.Check(context.Background(), RestoreRequest{Kind: "pgdmp"})
+	if err == nil || !strings.Contains(err.Error(), "not a SQL script") {
+		t.Fatalf("expected stream kind rejection, got %v", err)
+	}
+
+	if err := (MySQLRestorer{}).Check(context.Background(), RestoreRequest{Kind: "sql"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMySQLBackupperUsesToolLookupHook(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+	execLookPath = func(file string) (string, error) { return "", errors.New("stubbed: " + file) }
+
+	_, err := MySQLBackupper{}.Backup(context.Background(), config.DatabaseConfig{Type: "mysql"})
+	if err == nil || !strings.Contains(err.Error(), "stubbed: mysqldump") {
+		t.Fatalf("expected the stubbed lookup to fail the backup, got %v", err)
+	}
+}
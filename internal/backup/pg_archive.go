@@ -0,0 +1,224 @@
+package backup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var pgdmpMagic = []byte("PGDMP")
+
+// pgArchive is how pg_restore reads a decoded archive.
+type pgArchive struct {
+	format string // pg_restore --format: custom, tar or directory
+	path   string // archive file or directory; empty means the decoded stream on stdin
+}
+
+// pgArchiveFormat maps a decoded stream kind to the pg_restore --format it needs.
+func pgArchiveFormat(kind string) string {
+	switch kind {
+	case "pgtar":
+		return "tar"
+	case "dirtar", "dirzip":
+		return "directory"
+	default:
+		return "custom"
+	}
+}
+
+// extractDirectoryArchive unpacks a tarred or zipped directory-format dump under
+// tempDir and returns the directory holding toc.dat. cleanup removes everything
+// that was extracted and is safe to call more than once.
+func extractDirectoryArchive(src io.Reader, kind, tempDir string, minFree int64) (string, func(), error) {
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	if free, ok := diskFree(tempDir); ok && free < minFree {
+		return "", nil, fmt.Errorf(
+			"not enough free space in %s: need at least %d bytes, have %d (choose another --temp-dir)",
+			tempDir,
+			minFree,
+			free,
+		)
+	}
+
+	root, err := os.MkdirTemp(tempDir, "backupkit-restore-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(root) }
+
+	switch kind {
+	case "dirtar":
+		err = extractTar(src, root)
+	case "dirzip":
+		err = extractZip(src, root)
+	default:
+		err = fmt.Errorf("unsupported container kind %q", kind)
+	}
+	if err != nil {
+		cleanup()
+		if errors.Is(err, syscall.ENOSPC) {
+			return "", nil, fmt.Errorf("temp dir %s ran out of space while extracting (choose another --temp-dir): %w", tempDir, err)
+		}
+		return "", nil, err
+	}
+
+	dir, err := findTOCDir(root)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+func extractTar(src io.Reader, root string) error {
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar: %w", err)
+		}
+
+		target, err := safeJoin(root, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o700); err != nil {
+				return fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+		case tar.TypeReg:
+			if err := writeExtracted(target, tr); err != nil {
+				return fmt.Errorf("extract %s: %w", hdr.Name, err)
+			}
+		default:
+			return fmt.Errorf("extract %s: unsupported tar entry type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
+// extractZip spools src next to the extraction root because zip needs random access.
+func extractZip(src io.Reader, root string) error {
+	spool, err := os.CreateTemp(root, ".archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, src)
+	if err != nil {
+		return fmt.Errorf("write temp: %w", err)
+	}
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("read zip: %w", err)
+	}
+
+	for _, zf := range zr.File {
+		target, err := safeJoin(root, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0o700); err != nil {
+				return fmt.Errorf("extract %s: %w", zf.Name, err)
+			}
+		case mode.IsRegular():
+			rc, err := zf.Open()
+			if err != nil {
+				return fmt.Errorf("extract %s: %w", zf.Name, err)
+			}
+			err = writeExtracted(target, rc)
+			_ = rc.Close()
+			if err != nil {
+				return fmt.Errorf("extract %s: %w", zf.Name, err)
+			}
+		default:
+			return fmt.Errorf("extract %s: unsupported zip entry mode %s", zf.Name, mode)
+		}
+	}
+	return nil
+}
+
+// safeJoin resolves an archive member name under root, rejecting absolute paths
+// and names that escape root.
+func safeJoin(root, name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if slashed == "" || path.IsAbs(slashed) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("extract: unsafe archive member %q", name)
+	}
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("extract: unsafe archive member %q", name)
+		}
+	}
+	return filepath.Join(root, filepath.FromSlash(path.Clean(slashed))), nil
+}
+
+func writeExtracted(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// findTOCDir returns the shallowest directory under root that contains a
+// pg_dump toc.dat.
+func findTOCDir(root string) (string, error) {
+	best, bestDepth := "", -1
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "toc.dat" {
+			return nil
+		}
+		depth := strings.Count(filepath.ToSlash(p), "/")
+		if bestDepth < 0 || depth < bestDepth {
+			best, bestDepth = filepath.Dir(p), depth
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("scan extracted archive: %w", err)
+	}
+	if best == "" {
+		return "", fmt.Errorf("archive does not contain a pg_dump directory-format dump (no toc.dat)")
+	}
+
+	hdr := make([]byte, len(pgdmpMagic))
+	f, err := os.Open(filepath.Join(best, "toc.dat"))
+	if err != nil {
+		return "", fmt.Errorf("open toc.dat: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.ReadFull(f, hdr); err != nil || !bytes.Equal(hdr, pgdmpMagic) {
+		return "", fmt.Errorf("%s is not a pg_dump table of contents", filepath.Join(best, "toc.dat"))
+	}
+	return best, nil
+}
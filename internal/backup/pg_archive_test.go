@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pgdmpHeader returns a PGDMP header with the given archive format byte.
+func pgdmpHeader(format byte) []byte {
+	return append([]byte("PGDMP"), 1, 14, 0, 4, 8, format, 0, 0)
+}
+
+func tarBytes(t *testing.T, files map[string][]byte, order ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		if strings.HasSuffix(name, "/") {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractDirectoryArchiveFindsTOC(t *testing.T) {
+	files := map[string][]byte{
+		"backups/app/toc.dat":     pgdmpHeader(5),
+		"backups/app/3001.dat.gz": []byte("data"),
+	}
+	for kind, data := range map[string][]byte{
+		"dirtar": tarBytes(t, files, "backups/", "backups/app/", "backups/app/toc.dat", "backups/app/3001.dat.gz"),
+		"dirzip": zipBytes(t, files),
+	} {
+		t.Run(kind, func(t *testing.T) {
+			tmp := t.TempDir()
+			dir, cleanup, err := extractDirectoryArchive(bytes.NewReader(data), kind, tmp, 0)
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			if filepath.Base(dir) != "app" {
+				t.Fatalf("expected toc dir .../app, got %s", dir)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "3001.dat.gz")); err != nil {
+				t.Fatalf("expected data file: %v", err)
+			}
+			cleanup()
+			entries, _ := os.ReadDir(tmp)
+			if len(entries) != 0 {
+				t.Fatalf("expected temp dir to be empty after cleanup, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestExtractDirectoryArchiveRejectsUnsafeMembers(t *testing.T) {
+	data := tarBytes(t, map[string][]byte{"../evil": []byte("x")}, "../evil")
+	tmp := t.TempDir()
+	_, _, err := extractDirectoryArchive(bytes.NewReader(data), "dirtar", tmp, 0)
+	if err == nil || !strings.Contains(err.Error(), "unsafe archive member") {
+		t.Fatalf("expected unsafe member error, got %v", err)
+	}
+
+	data = tarBytes(t, map[string][]byte{"notes.txt": []byte("x")}, "notes.txt")
+	_, _, err = extractDirectoryArchive(bytes.NewReader(data), "dirtar", tmp, 0)
+	if err == nil || !strings.Contains(err.Error(), "no toc.dat") {
+		t.Fatalf("expected missing toc.dat error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// pgConnArgs are the connection flags shared by the libpq tools. dbFlag names
+// the tool's database flag ("--dbname", or "--database" for pg_dumpall); ""
+// leaves the database out. Empty settings are omitted so a service or the PG*
+// environment can supply them. A URI is passed as --dbname without its
+// password, which pgEnv puts in PGPASSWORD instead.
+func pgConnArgs(conn config.ConnectionConfig, dbFlag string) []string {
+	if conn.URI != "" {
+		uri, _, _ := splitPgURIPassword(conn.URI)
+		return []string{"--dbname", uri}
+	}
+	var args []string
+	if conn.Host != "" {
+		args = append(args, "--host", conn.Host)
+	}
+	if conn.Port != 0 {
+		args = append(args, "--port", strconv.Itoa(conn.Port))
+	}
+	if dbFlag != "" && conn.Database != "" {
+		args = append(args, dbFlag, conn.Database)
+	}
+	if conn.User != "" {
+		args = append(args, "--username", conn.User)
+	}
+	return args
+}
+
+// pgEnv passes the password and the TLS and session settings to libpq tools
+// through the environment, which keeps them out of argv and the printed plan.
+func pgEnv(conn config.ConnectionConfig) []string {
+	env := os.Environ()
+	password := conn.Password
+	if conn.URI != "" {
+		if _, p, ok := splitPgURIPassword(conn.URI); ok {
+			password = p
+		}
+	}
+	for _, kv := range []struct{ key, value string }{
+		{"PGPASSWORD", password},
+		{"PGSERVICE", conn.Service},
+		{"PGSSLMODE", conn.SSLMode},
+		{"PGSSLROOTCERT", conn.SSLRootCert},
+		{"PGSSLCERT", conn.SSLCert},
+		{"PGSSLKEY", conn.SSLKey},
+		{"PGAPPNAME", conn.ApplicationName},
+		{"PGOPTIONS", conn.Options},
+	} {
+		if kv.value != "" {
+			env = append(env, kv.key+"="+kv.value)
+		}
+	}
+	return env
+}
+
+// splitPgURIPassword removes the password from a connection URI, from its user
+// info or a password query parameter, and reports whether there was one.
+func splitPgURIPassword(uri string) (string, string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri, "", false
+	}
+	password, ok := u.User.Password()
+	if ok {
+		u.User = url.User(u.User.Username())
+	}
+	q := u.Query()
+	if q.Has("password") {
+		password, ok = q.Get("password"), true
+		q.Del("password")
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), password, ok
+}
+
+// postgresTarget is redactedTarget for postgres connections, which may also be
+// given as a URI or a service name.
+func postgresTarget(conn config.ConnectionConfig) string {
+	switch {
+	case conn.URI != "":
+		uri, _, _ := splitPgURIPassword(conn.URI)
+		return uri
+	case conn.Service != "" && conn.Host == "":
+		u := url.URL{Scheme: "postgresql", Path: "/" + conn.Database, RawQuery: "service=" + url.QueryEscape(conn.Service)}
+		if conn.User != "" {
+			u.User = url.User(conn.User)
+		}
+		return u.String()
+	}
+	return redactedTarget("postgresql", conn)
+}
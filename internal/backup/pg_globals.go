@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// PostgresGlobalsBackupper dumps cluster-wide objects (roles, role memberships
+// and tablespaces) that per-database pg_dump archives never contain.
+type PostgresGlobalsBackupper struct{}
+
+func (backup PostgresGlobalsBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	pgDumpall, err := lookPgTool(cfg, "pg_dumpall")
+	if err != nil {
+		return nil, err
+	}
+	conn := cfg.Connection
+
+	cmd := exec.CommandContext(
+		ctx,
+		pgDumpall,
+		append([]string{"--globals-only"}, pgConnArgs(conn, "--database")...)...,
+	)
+	cmd.Env = pgEnv(conn)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("pg_dumpall failed: %w : %s", err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+// globalsPsqlArgs connects like psqlArgs but keeps going after errors: a globals
+// script always creates roles that exist already, at least the superuser.
+func globalsPsqlArgs(conn config.ConnectionConfig) []string {
+	return append(pgConnArgs(conn, "--dbname"), "--quiet")
+}
+
+// RestoreGlobals applies req.Globals with psql. Statement errors are printed as
+// warnings; only failing to run psql at all fails the restore.
+func (PostgresRestorer) RestoreGlobals(ctx context.Context, req RestoreRequest) error {
+	conn := req.DB.Connection
+	cmd := exec.CommandContext(ctx, pgTool(req.DB, "psql"), globalsPsqlArgs(conn)...)
+	cmd.Env = pgEnv(conn)
+	cmd.Stdin = req.Globals
+	cmd.Stdout = io.Discard
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("restore/globals/psql: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	warnings := 0
+	sc := bufio.NewScanner(&stderr)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			fmt.Fprintf(req.Out, "warning: globals: %s\n", line)
+			warnings++
+		}
+	}
+	fmt.Fprintf(req.Out, "globals OK: db=%s from=%s warnings=%d\n", req.DB.Name, req.GlobalsFrom, warnings)
+	return nil
+}
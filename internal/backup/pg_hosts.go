@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// hostProbeTimeout bounds one host probe, including the connection attempt.
+const hostProbeTimeout = 10 * time.Second
+
+// hostProbeSQL reports whether the server is a standby and how far it is behind.
+// A standby that has replayed everything it received has no lag, even when the
+// primary has been idle since the last replayed transaction.
+const hostProbeSQL = `SELECT pg_is_in_recovery(),
+  CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+  ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// HostProbe is the probed state of one connection.hosts entry.
+type HostProbe struct {
+	Host    string
+	Port    int
+	Standby bool
+	Lag     time.Duration
+	Err     error
+}
+
+// Addr renders the host as host:port, or just the host when no port is set.
+func (p HostProbe) Addr() string {
+	if p.Port == 0 {
+		return p.Host
+	}
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+func (p HostProbe) String() string {
+	switch {
+	case p.Err != nil:
+		return p.Addr() + ": " + p.Err.Error()
+	case p.Standby:
+		return p.Addr() + " (standby, lag " + p.Lag.Round(time.Millisecond).String() + ")"
+	}
+	return p.Addr() + " (primary)"
+}
+
+// SelectPostgresHost probes connection.hosts in order and returns db pointed at
+// the chosen host (see config.ConnectionConfig.Prefer). Databases without hosts
+// are returned unchanged with a zero HostProbe.
+func SelectPostgresHost(ctx context.Context, db config.DatabaseConfig, prefer string) (config.DatabaseConfig, HostProbe, error) {
+	conn := db.Connection
+	if len(conn.Hosts) == 0 {
+		return db, HostProbe{}, nil
+	}
+	psql, err := lookPgTool(db, "psql")
+	if err != nil {
+		return db, HostProbe{}, fmt.Errorf("probe connection.hosts: %w", err)
+	}
+	maxLag, _ := conn.ReplayLag()
+
+	var tried []string
+	var fallback *HostProbe
+	for _, entry := range conn.Hosts {
+		host, port, err := config.SplitHostEntry(entry, conn.Port)
+		p := HostProbe{Host: host, Port: port, Err: err}
+		if err == nil {
+			p = probePostgresHost(ctx, psql, conn, host, port)
+		}
+		if ctx.Err() != nil {
+			return db, HostProbe{}, ctx.Err()
+		}
+		switch {
+		case p.Err != nil:
+		case p.Standby && maxLag > 0 && p.Lag > maxLag:
+			p.Err = fmt.Errorf("standby replay lag %s exceeds max_replay_lag %s", p.Lag.Round(time.Millisecond), maxLag)
+		case p.Standby && prefer == "primary":
+			p.Err = fmt.Errorf("standby, a primary is required")
+		case !p.Standby && prefer == "standby":
+			if fallback == nil {
+				fallback = &p
+			}
+		default:
+			return withHost(db, p), p, nil
+		}
+		tried = append(tried, p.String())
+	}
+	if fallback != nil {
+		return withHost(db, *fallback), *fallback, nil
+	}
+	return db, HostProbe{}, fmt.Errorf("no usable postgres host: %s", strings.Join(tried, "; "))
+}
+
+func withHost(db config.DatabaseConfig, p HostProbe) config.DatabaseConfig {
+	db.Connection.Host = p.Host
+	db.Connection.Port = p.Port
+	db.Connection.Hosts = nil
+	return db
+}
+
+// probePostgresHost connects to one host with psql and runs hostProbeSQL.
+func probePostgresHost(ctx context.Context, psql string, conn config.ConnectionConfig, host string, port int) HostProbe {
+	p := HostProbe{Host: host, Port: port}
+	conn.Host, conn.Port, conn.Hosts = host, port, nil
+
+	ctx, cancel := context.WithTimeout(ctx, hostProbeTimeout)
+	defer cancel()
+	args := append(pgConnArgs(conn, "--dbname"), "-X", "-A", "-t", "-q", "-c", hostProbeSQL)
+	cmd := exec.CommandContext(ctx, psql, args...)
+	cmd.Env = pgEnv(conn)
+	if os.Getenv("PGCONNECT_TIMEOUT") == "" {
+		cmd.Env = append(cmd.Env, "PGCONNECT_TIMEOUT=5")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		p.Err = fmt.Errorf("probe failed: %s", msg)
+		return p
+	}
+
+	standby, lag, ok := strings.Cut(strings.TrimSpace(stdout.String()), "|")
+	secs, err := strconv.ParseFloat(lag, 64)
+	if !ok || err != nil || (standby != "t" && standby != "f") {
+		p.Err = fmt.Errorf("probe failed: unexpected output %q", strings.TrimSpace(stdout.String()))
+		return p
+	}
+	p.Standby = standby == "t"
+	p.Lag = time.Duration(secs * float64(time.Second))
+	return p
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// postgresPhysicalEngine serves postgres databases with mode: physical. It is not
+// registered under its own type; postgresVariant picks it from the database config.
+var postgresPhysicalEngine = Engine{
+	Backupper: PostgresPhysicalBackupper{},
+	Restorer:  PostgresPhysicalRestorer{},
+	Ext:       ".tar",
+	Header:    "tar",
+}
+
+// PostgresPhysicalBackupper streams a base backup of the whole cluster as one tar
+// with the WAL needed to make it consistent.
+type PostgresPhysicalBackupper struct{}
+
+func (backup PostgresPhysicalBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	pgBasebackup, err := lookPgTool(cfg, "pg_basebackup")
+	if err != nil {
+		return nil, err
+	}
+	conn := cfg.Connection
+
+	cmd := exec.CommandContext(ctx, pgBasebackup, pgBasebackupArgs(conn)...)
+	cmd.Env = pgEnv(conn)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("pg_basebackup failed: %w : %s", err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+// pgBasebackupArgs writes a single tar to stdout; -X fetch puts the WAL into that
+// tar instead of a second stream. Clusters with extra tablespaces are rejected by
+// pg_basebackup, since those would need one tar each.
+func pgBasebackupArgs(conn config.ConnectionConfig) []string {
+	return append(pgConnArgs(conn, ""), "--format=tar", "--pgdata=-", "--wal-method=fetch")
+}
+
+// physicalRecoveryConf is appended to postgresql.auto.conf. Together with
+// recovery.signal the server replays WAL and promotes: without an archive it
+// stops as soon as the backup is consistent, with one at TargetTime or at the
+// end of the archive.
+func physicalRecoveryConf(o RestoreOptions) string {
+	var b strings.Builder
+	b.WriteString("\n# Added by backupkit restore.\n")
+	if len(o.WALFetch) == 0 {
+		// Archive recovery requires a restore_command; there is no archive, so it always fails.
+		b.WriteString("restore_command = 'false'\n")
+		b.WriteString("recovery_target = 'immediate'\n")
+	} else {
+		fmt.Fprintf(&b, "restore_command = %s\n", confQuote(shellJoin(o.WALFetch)+" %f %p"))
+		if o.TargetTime != "" {
+			fmt.Fprintf(&b, "recovery_target_time = %s\n", confQuote(o.TargetTime))
+		}
+	}
+	b.WriteString("recovery_target_action = 'promote'\n")
+	return b.String()
+}
+
+// confQuote quotes a postgresql.conf string value.
+func confQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// PostgresPhysicalRestorer extracts a base backup into an empty data directory
+// given by --out. It does not start or stop PostgreSQL.
+type PostgresPhysicalRestorer struct{}
+
+var (
+	_ FileRestorer = PostgresPhysicalRestorer{}
+	_ WALRestorer  = PostgresPhysicalRestorer{}
+)
+
+// ReplaysWAL reports whether db archives WAL that a restore can replay.
+func (PostgresPhysicalRestorer) ReplaysWAL(db config.DatabaseConfig) bool {
+	return db.Postgres != nil && db.Postgres.WALArchive
+}
+
+func (PostgresPhysicalRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for physical backups; they restore the whole cluster", strings.Join(set, ", "))
+	}
+	if req.Options.AllowSQLFallback {
+		return fmt.Errorf("restore: --allow-sql-fallback not supported for physical backups")
+	}
+	if req.Options.Clean {
+		return fmt.Errorf("restore: --clean not supported for physical backups; move the old data directory aside instead")
+	}
+	if req.Options.TargetTime != "" && len(req.Options.WALFetch) == 0 {
+		return fmt.Errorf("restore: --target-time needs postgres.wal_archive; a base backup alone only restores to its end")
+	}
+	if req.Kind != "basetar" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a pg_basebackup tar (got %s)", req.Kind)
+	}
+	dir := req.Options.OutPath
+	if dir == "" {
+		return fmt.Errorf("restore: --out <data directory> is required for physical backups")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("restore: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("restore: data directory %s is not empty", dir)
+	}
+	return nil
+}
+
+func (PostgresPhysicalRestorer) Target(db config.DatabaseConfig) string {
+	conn := db.Connection
+	conn.Database = ""
+	return postgresTarget(conn) + " (physical)"
+}
+
+func (PostgresPhysicalRestorer) OutputPath(req RestoreRequest) string {
+	return req.Options.OutPath
+}
+
+func (PostgresPhysicalRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	var sum filesSummary
+	label := false
+	tr := tar.NewReader(req.Stream)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("restore/tar: %w", err)
+		}
+		if filepath.Clean(hdr.Name) == "backup_label" {
+			label = true
+		}
+		sum.count(hdr)
+	}
+	if !label {
+		return fmt.Errorf("restore/sniff: tar has no backup_label; not a pg_basebackup")
+	}
+	fmt.Fprintf(w, "  entries:  %s\n", sum)
+	for _, line := range strings.Split(strings.TrimSpace(physicalRecoveryConf(req.Options)), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			fmt.Fprintf(w, "  recovery: %s\n", line)
+		}
+	}
+	return nil
+}
+
+func (PostgresPhysicalRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	dir := req.Options.OutPath
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("restore/physical: %w", err)
+	}
+	sum, err := extractFiles(ctx, req.Stream, dir, false)
+	if err != nil {
+		return fmt.Errorf("restore/physical: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "backup_label")); err != nil {
+		return fmt.Errorf("restore/physical: extracted tar has no backup_label; not a pg_basebackup: %w", err)
+	}
+	if err := writeRecoveryConf(dir, physicalRecoveryConf(req.Options)); err != nil {
+		return fmt.Errorf("restore/physical: %w", err)
+	}
+	// PostgreSQL refuses to start on a data directory group or world can read.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return fmt.Errorf("restore/physical: %w", err)
+	}
+	fmt.Fprintf(req.Out, "restore OK: db=%s from=%s datadir=%s %s\n", req.DB.Name, req.From, dir, sum)
+	fmt.Fprintf(req.Out, "start PostgreSQL on it (pg_ctl -D %s start); it promotes when WAL replay ends\n", dir)
+	return nil
+}
+
+func writeRecoveryConf(dir, conf string) error {
+	if err := os.WriteFile(filepath.Join(dir, "recovery.signal"), nil, 0o600); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "postgresql.auto.conf"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, conf); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+)
+
+// pgRestoreEntry matches pg_restore --verbose lines that name the object being restored,
+// e.g. `pg_restore: processing data for table "public.users"`.
+var pgRestoreEntry = regexp.MustCompile(`^pg_restore: (creating|processing|dropping|restoring|executing|finished item) (.+)$`)
+
+// pgRestoreInfo matches other informational --verbose lines that are not worth
+// keeping for error messages.
+var pgRestoreInfo = regexp.MustCompile(`^pg_restore: (connecting|launching|finished|implied|setting|processing|disabling|enabling|entering|skipping)`)
+
+// pgRestoreStderr returns the writer for pg_restore's stderr and a flush to call
+// once pg_restore has exited. Without progress the writer is buf itself; with
+// progress, --verbose entry lines update the current entry and only
+// non-informational lines are kept in buf for error messages. flush handles a
+// last line without a trailing newline.
+func pgRestoreStderr(p Progress, buf *bytes.Buffer) (w io.Writer, flush func()) {
+	if p == nil {
+		return buf, func() {}
+	}
+	ps := &progressStderr{p: p, keep: buf}
+	return ps, ps.flush
+}
+
+type progressStderr struct {
+	p       Progress
+	keep    *bytes.Buffer
+	partial []byte
+}
+
+func (w *progressStderr) Write(b []byte) (int, error) {
+	w.partial = append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.handleLine(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+func (w *progressStderr) flush() {
+	if len(w.partial) > 0 {
+		w.handleLine(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *progressStderr) handleLine(line string) {
+	if m := pgRestoreEntry.FindStringSubmatch(line); m != nil {
+		w.p.SetEntry(m[1] + " " + m[2])
+		return
+	}
+	if pgRestoreInfo.MatchString(line) {
+		return
+	}
+	w.keep.WriteString(line)
+	w.keep.WriteByte('\n')
+}
@@ -0,0 +1,49 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type recordingProgress struct{ phase, entry string }
+
+func (p *recordingProgress) SetPhase(phase string) { p.phase = phase }
+func (p *recordingProgress) SetEntry(entry string) { p.entry = entry }
+
+func TestPgRestoreStderrTracksEntryAndKeepsErrors(t *testing.T) {
+	p := &recordingProgress{}
+	var keep bytes.Buffer
+	w, _ := pgRestoreStderr(p, &keep)
+
+	io.WriteString(w, "pg_restore: connecting to database for restore\npg_restore: processing data for table \"public.us")
+	io.WriteString(w, "ers\"\npg_restore: error: relation \"users\" already exists\n")
+
+	if p.entry != `processing data for table "public.users"` {
+		t.Fatalf("unexpected entry %q", p.entry)
+	}
+	if got := keep.String(); got != "pg_restore: error: relation \"users\" already exists\n" {
+		t.Fatalf("unexpected kept stderr %q", got)
+	}
+}
+
+func TestPgRestoreStderrWithoutProgressKeepsEverything(t *testing.T) {
+	var buf bytes.Buffer
+	if w, _ := pgRestoreStderr(nil, &buf); w != io.Writer(&buf) {
+		t.Fatal("expected stderr buffer to be used directly")
+	}
+}
+
+func TestPgRestoreStderrFlushKeepsUnterminatedLastLine(t *testing.T) {
+	var keep bytes.Buffer
+	w, flush := pgRestoreStderr(&recordingProgress{}, &keep)
+
+	io.WriteString(w, "pg_restore: processing data for table \"public.users\"\npg_restore: error: could not read from input file: end of file")
+	if keep.Len() != 0 {
+		t.Fatalf("expected the unterminated line to wait for flush, got %q", keep.String())
+	}
+	flush()
+	if got := keep.String(); got != "pg_restore: error: could not read from input file: end of file\n" {
+		t.Fatalf("unexpected kept stderr %q", got)
+	}
+}
@@ -0,0 +1,497 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("postgres", Engine{
+		Backupper: PostgresBackupper{},
+		Restorer:  PostgresRestorer{},
+		Ext:       ".dump",
+		Header:    "pgdmp",
+		Variant:   postgresVariant,
+	})
+}
+
+// postgresVariant serves databases in physical mode with pg_basebackup instead
+// of pg_dump; with jobs above 1 logical backups store a tar of a
+// directory-format dump.
+func postgresVariant(e Engine, db config.DatabaseConfig) Engine {
+	switch {
+	case db.Postgres == nil:
+	case db.Postgres.Mode == "physical":
+		return postgresPhysicalEngine
+	case db.Postgres.Jobs > 1:
+		e.Ext, e.Header = ".tar", "tar"
+	}
+	return e
+}
+
+// PostgresRestorer restores pg_dump archives with pg_restore and, with
+// AllowSQLFallback, plain SQL dumps with psql.
+type PostgresRestorer struct{}
+
+func isPgArchive(kind string) bool {
+	switch kind {
+	case "pgdmp", "pgtar", "dirtar", "dirzip":
+		return true
+	}
+	return false
+}
+
+func (PostgresRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	opts := req.Options
+	switch {
+	case isPgArchive(req.Kind):
+	case req.Kind == "sql":
+		if !opts.AllowSQLFallback {
+			return fmt.Errorf("restore/sniff: decoded stream looks like SQL text; rerun with --allow-sql-fallback to restore with psql")
+		}
+		if opts.Jobs > 1 {
+			return fmt.Errorf("restore: --jobs requires a pg_dump archive; SQL text is restored serially by psql")
+		}
+		if opts.selective() {
+			return fmt.Errorf("restore: selective restore options (--schema, --table, --schema-only, --data-only, --list, --use-list) require a pg_dump archive, got SQL text")
+		}
+	case req.Kind == "basetar":
+		return fmt.Errorf("restore/sniff: decoded stream is a pg_basebackup tar; set postgres.mode: physical and restore with --out <data directory>")
+	default:
+		return fmt.Errorf("restore/sniff: decoded stream is neither a pg_dump archive (custom, tar or directory) nor recognizable SQL text")
+	}
+
+	if req.Globals != nil {
+		if _, err := lookPgTool(req.DB, "psql"); err != nil {
+			return err
+		}
+	}
+
+	if req.Kind == "pgtar" && opts.Jobs > 1 {
+		return fmt.Errorf("restore: --jobs is not supported for pg_dump tar archives; pg_restore restores them serially")
+	}
+	if _, err := parseRoleMap(opts.RoleMap); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	if len(opts.RoleMap) > 0 && opts.Jobs > 1 {
+		return fmt.Errorf("restore: --role-map restores through psql and cannot be combined with --jobs")
+	}
+
+	if err := validateRestoreToolAvailability(req.DB, req.Kind); err != nil {
+		return err
+	}
+	if err := checkArchiveVersion(ctx, req.DB, peekArchiveDumpVersion(req.Kind, req.Stream), req.Out); err != nil {
+		return err
+	}
+	if req.Kind != "sql" && len(opts.RoleMap) > 0 {
+		return validateRestoreToolAvailability(req.DB, "sql")
+	}
+	return nil
+}
+
+func (PostgresRestorer) Target(db config.DatabaseConfig) string {
+	return postgresTarget(db.Connection)
+}
+
+func (PostgresRestorer) List(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	if !isPgArchive(req.Kind) {
+		return fmt.Errorf("restore: --list requires a pg_dump archive, got SQL text")
+	}
+	archive, stream, cleanup, err := openPgArchive(req)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return runPgRestoreList(ctx, req.DB, archive, stream, w)
+}
+
+func (PostgresRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	opts := req.Options
+	conn := req.DB.Connection
+
+	if req.Globals != nil {
+		fmt.Fprintf(w, "  globals:  psql %s < %s (errors for existing roles are warnings)\n", shellJoin(globalsPsqlArgs(conn)), req.GlobalsFrom)
+	}
+
+	if req.Kind == "sql" {
+		if rw := roleRewriteFor(opts); rw.active() {
+			fmt.Fprintf(w, "  rewrite:  %s\n", describeRoleRewrite(rw))
+		}
+		if opts.Role != "" {
+			fmt.Fprintf(w, "  role:     SET ROLE %s\n", quoteIdent(opts.Role))
+		}
+		fmt.Fprintf(w, "  command:  psql %s\n", shellJoin(psqlArgs(conn)))
+		if opts.Clean {
+			fmt.Fprintln(w, "  clean:    ignored for psql fallback")
+		}
+		fmt.Fprintln(w, "  toc:      n/a (plain SQL stream)")
+		return nil
+	}
+
+	archive, stream, cleanup, err := openPgArchive(req)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rw := roleRewriteFor(opts)
+	if len(rw.roleMap) > 0 {
+		fmt.Fprintf(w, "  convert:  pg_restore %s\n", shellJoin(pgRestoreScriptArgs(opts, archive)))
+		fmt.Fprintf(w, "  rewrite:  %s\n", describeRoleRewrite(rw))
+		fmt.Fprintf(w, "  command:  psql %s\n", shellJoin(psqlArgs(conn)))
+	} else {
+		if rw.active() {
+			fmt.Fprintf(w, "  rewrite:  %s\n", describeRoleRewrite(rw))
+		}
+		planArchive := archive
+		if opts.Jobs > 1 && archive.path == "" {
+			planArchive.path = "<temp-file>"
+		}
+		fmt.Fprintf(w, "  command:  pg_restore %s\n", shellJoin(pgRestoreArgs(conn, opts, planArchive, req.Progress != nil)))
+	}
+	if opts.Clean {
+		fmt.Fprintln(w, "  clean:    existing objects in the archive will be dropped first")
+	}
+	if archive.format == "directory" {
+		fmt.Fprintf(w, "  extract:  %s (removed after the dry run)\n", archive.path)
+	} else if opts.Jobs > 1 && len(rw.roleMap) == 0 {
+		dir := opts.TempDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		if free, ok := diskFree(dir); ok {
+			fmt.Fprintf(w, "  spool:    %s (free=%d bytes, need at least %d)\n", dir, free, req.StoredSize)
+		} else {
+			fmt.Fprintf(w, "  spool:    %s\n", dir)
+		}
+	}
+
+	var toc bytes.Buffer
+	if err := runPgRestoreList(ctx, req.DB, archive, stream, &toc); err != nil {
+		return err
+	}
+	total, counts := summarizeTOC(&toc)
+	fmt.Fprintf(w, "  toc:      %d entries\n", total)
+	for _, c := range counts {
+		fmt.Fprintf(w, "    %-26s %d\n", c.kind, c.n)
+	}
+	return nil
+}
+
+func (r PostgresRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	opts := req.Options
+	conn := req.DB.Connection
+	out := req.Out
+	var cs CloseStack
+	defer cs.CloseAll()
+
+	if isPgArchive(req.Kind) {
+		v, err := checkRestoreServerVersion(ctx, req.DB, out)
+		if err != nil {
+			return err
+		}
+		if opts.Verbose && v.Server != "" {
+			fmt.Fprintf(out, "versions: db=%s client=%s server=%s\n", req.DB.Name, v.Client, v.Server)
+		}
+	}
+
+	if req.Globals != nil {
+		if req.Progress != nil {
+			req.Progress.SetPhase("globals")
+		}
+		if err := r.RestoreGlobals(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	if req.Kind == "sql" {
+		if opts.Clean {
+			fmt.Fprintln(os.Stderr, "warning: --clean is ignored when falling back to psql")
+		}
+		if opts.Verbose {
+			fmt.Fprintf(out, "restore tool fallback: db=%s tool=psql\n", req.DB.Name)
+		}
+		return runStdinRestore(ctx, pgTool(req.DB, "psql"), psqlArgs(conn), pgEnv(conn), ownershipSQLReader(req.Stream, opts, &cs), &cs, req)
+	}
+
+	archive, stream, cleanup, err := openPgArchive(req)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if archive.format == "directory" {
+		if opts.Verbose {
+			fmt.Fprintf(out, "restore extract: db=%s kind=%s dir=%s\n", req.DB.Name, req.Kind, archive.path)
+		}
+		if err := checkArchiveVersion(ctx, req.DB, tocDumpVersion(archive.path), out); err != nil {
+			return err
+		}
+	}
+
+	// Role renames need the SQL text: convert with pg_restore --file=- and replay via psql.
+	if len(opts.RoleMap) > 0 {
+		rw := roleRewriteFor(opts)
+		if opts.Verbose {
+			fmt.Fprintf(out, "restore role map: db=%s tool=pg_restore|psql %s\n", req.DB.Name, describeRoleRewrite(rw))
+		}
+		script := pgRestoreScriptReader(ctx, pgTool(req.DB, "pg_restore"), stream, pgRestoreScriptArgs(opts, archive), &cs)
+		return runStdinRestore(ctx, pgTool(req.DB, "psql"), psqlArgs(conn), pgEnv(conn), rewriteSQLReader(script, rw, &cs), &cs, req)
+	}
+
+	// pg_restore cannot run workers against stdin, so spool the decoded archive first.
+	if opts.Jobs > 1 && archive.path == "" {
+		setPhase(req.Progress, "spooling")
+		spoolPath, cleanup, err := spoolToTempFile(stream, opts.TempDir, req.StoredSize)
+		setPhase(req.Progress, "restoring")
+		if err != nil {
+			return fmt.Errorf("restore/spool: %w", err)
+		}
+		defer cleanup()
+		if opts.Verbose {
+			fmt.Fprintf(out, "restore spool: db=%s path=%s jobs=%d\n", req.DB.Name, spoolPath, opts.Jobs)
+		}
+		archive.path = spoolPath
+		stream = nil
+	}
+
+	args := pgRestoreArgs(conn, opts, archive, req.Progress != nil)
+	if err := runPgRestore(ctx, args, req.DB, stream, req.Progress); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "restore OK: db=%s from=%s\n", req.DB.Name, req.From)
+	return nil
+}
+
+func setPhase(p Progress, phase string) {
+	if p != nil {
+		p.SetPhase(phase)
+	}
+}
+
+// openPgArchive makes the decoded stream readable by pg_restore. Custom and tar
+// archives stay on stdin; tarred or zipped directory-format dumps are extracted
+// under TempDir and the returned stream is nil.
+func openPgArchive(req RestoreRequest) (pgArchive, io.Reader, func(), error) {
+	archive := pgArchive{format: pgArchiveFormat(req.Kind)}
+	if archive.format != "directory" {
+		return archive, req.Stream, func() {}, nil
+	}
+	dir, cleanup, err := extractDirectoryArchive(req.Stream, req.Kind, req.Options.TempDir, req.StoredSize)
+	if err != nil {
+		return pgArchive{}, nil, nil, fmt.Errorf("restore/extract: %w", err)
+	}
+	archive.path = dir
+	return archive, nil, cleanup, nil
+}
+
+func roleRewriteFor(opts RestoreOptions) roleRewrite {
+	roleMap, _ := parseRoleMap(opts.RoleMap)
+	return roleRewrite{roleMap: roleMap, noOwner: opts.NoOwner, noPrivileges: opts.NoPrivileges}
+}
+
+// runPgRestore runs pg_restore with args. When stream is nil the archive path is
+// expected as the last argument; otherwise the archive is fed on stdin.
+func runPgRestore(ctx context.Context, args []string, db config.DatabaseConfig, stream io.Reader, prog Progress) error {
+	cmd := exec.CommandContext(ctx, pgTool(db, "pg_restore"), args...)
+	cmd.Env = pgEnv(db.Connection)
+
+	var stderr bytes.Buffer
+	var flushStderr func()
+	cmd.Stderr, flushStderr = pgRestoreStderr(prog, &stderr)
+
+	var copyErr error
+	var waitErr error
+	if stream == nil {
+		waitErr = cmd.Run()
+	} else {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return fmt.Errorf("restore/pg_restore/stdin: %w", err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			_ = stdin.Close()
+			return fmt.Errorf("restore/pg_restore/start: %w", err)
+		}
+
+		_, copyErr = io.Copy(stdin, stream)
+		_ = stdin.Close()
+
+		waitErr = cmd.Wait()
+	}
+	flushStderr()
+
+	if copyErr != nil {
+		return fmt.Errorf("restore/stream: %w", copyErr)
+	}
+	if waitErr != nil {
+		pgErr := strings.TrimSpace(stderr.String())
+		if strings.Contains(pgErr, "already exists") {
+			return fmt.Errorf(
+				"restore/pg_restore/wait: %w: %s\nhint: target database is not empty. rerun with --clean or restore into a fresh database",
+				waitErr,
+				pgErr,
+			)
+		}
+		return fmt.Errorf("restore/pg_restore/wait: %w: %s", waitErr, pgErr)
+	}
+	return nil
+}
+
+// pgRestoreArgs builds the pg_restore argv for restoring archive; the archive
+// path, when set, is the last argument. verbose makes pg_restore name each TOC
+// entry on stderr for progress reporting.
+func pgRestoreArgs(conn config.ConnectionConfig, opts RestoreOptions, archive pgArchive, verbose bool) []string {
+	args := pgConnArgs(conn, "--dbname")
+	args = append(args, pgRestoreArchiveArgs(opts, archive.format)...)
+	if opts.Jobs > 1 {
+		args = append(args, "--jobs", strconv.Itoa(opts.Jobs))
+	}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+	if archive.path != "" {
+		args = append(args, archive.path)
+	}
+	return args
+}
+
+// pgRestoreScriptArgs converts the archive into a SQL script on stdout instead of
+// connecting; selection and ownership flags still apply.
+func pgRestoreScriptArgs(opts RestoreOptions, archive pgArchive) []string {
+	args := append([]string{"--file=-"}, pgRestoreArchiveArgs(opts, archive.format)...)
+	if archive.path != "" {
+		args = append(args, archive.path)
+	}
+	return args
+}
+
+// pgRestoreArchiveArgs are the format, selection and ownership flags shared by
+// direct restores and script conversion.
+func pgRestoreArchiveArgs(opts RestoreOptions, format string) []string {
+	args := []string{
+		"--format=" + format,
+		"--exit-on-error",
+	}
+	if opts.Clean {
+		args = append(args, "--clean", "--if-exists")
+	}
+	for _, s := range opts.Schemas {
+		args = append(args, "--schema", strings.TrimSpace(s))
+	}
+	for _, t := range opts.Tables {
+		args = append(args, "--table", strings.TrimSpace(t))
+	}
+	if opts.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+	if opts.DataOnly {
+		args = append(args, "--data-only")
+	}
+	if opts.UseList != "" {
+		args = append(args, "--use-list", opts.UseList)
+	}
+	if opts.NoOwner {
+		args = append(args, "--no-owner")
+	}
+	if opts.NoPrivileges {
+		args = append(args, "--no-privileges")
+	}
+	if opts.Role != "" {
+		args = append(args, "--role", opts.Role)
+	}
+	return args
+}
+
+// pgRestoreScriptReader streams the SQL script pg_restore renders from an archive.
+func pgRestoreScriptReader(ctx context.Context, pgRestore string, stream io.Reader, args []string, cs *CloseStack) io.Reader {
+	pr, pw := io.Pipe()
+	cs.Add(pr)
+
+	cmd := exec.CommandContext(ctx, pgRestore, args...)
+	cmd.Stdin = stream
+	cmd.Stdout = pw
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	go func() {
+		if err := cmd.Run(); err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("pg_restore script: %w: %s", err, strings.TrimSpace(stderr.String())))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr
+}
+
+// ownershipSQLReader applies --role and the ownership rewrites to a plain SQL stream.
+func ownershipSQLReader(stream io.Reader, opts RestoreOptions, cs *CloseStack) io.Reader {
+	if rw := roleRewriteFor(opts); rw.active() {
+		stream = rewriteSQLReader(stream, rw, cs)
+	}
+	if opts.Role != "" {
+		stream = io.MultiReader(strings.NewReader("SET ROLE "+quoteIdent(opts.Role)+";\n"), stream)
+	}
+	return stream
+}
+
+// psqlArgs builds the psql argv for replaying a plain SQL stream from stdin.
+func psqlArgs(conn config.ConnectionConfig) []string {
+	return append(pgConnArgs(conn, "--dbname"), "-v", "ON_ERROR_STOP=1")
+}
+
+// runPgRestoreList prints the archive table of contents without connecting to a database.
+func runPgRestoreList(ctx context.Context, db config.DatabaseConfig, archive pgArchive, stream io.Reader, out io.Writer) error {
+	args := []string{"--list", "--format=" + archive.format}
+	if archive.path != "" {
+		args = append(args, archive.path)
+	}
+	cmd := exec.CommandContext(ctx, pgTool(db, "pg_restore"), args...)
+	cmd.Stdin = stream
+	cmd.Stdout = out
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("restore/pg_restore/list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func validateRestoreToolAvailability(db config.DatabaseConfig, decodedKind string) error {
+	switch {
+	case decodedKind == "sql":
+		if _, err := lookPgTool(db, "psql"); err != nil {
+			return err
+		}
+	case isPgArchive(decodedKind):
+		if _, err := lookPgTool(db, "pg_restore"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported decoded stream kind %q", decodedKind)
+	}
+	return nil
+}
+
+var (
+	_ Lister          = PostgresRestorer{}
+	_ GlobalsRestorer = PostgresRestorer{}
+	_ UndoRestorer    = PostgresRestorer{}
+)
+
+// UndoArgs adds --clean so the snapshot replaces the restored objects.
+func (PostgresRestorer) UndoArgs() []string {
+	return []string{"--clean"}
+}
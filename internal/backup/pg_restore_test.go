@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestValidateRestoreToolAvailabilityPrefersPsqlForSQL(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+
+	var lookedUp []string
+	execLookPath = func(file string) (string, error) {
+		lookedUp = append(lookedUp, file)
+		return "/usr/bin/" + file, nil
+	}
+
+	if err := validateRestoreToolAvailability(config.DatabaseConfig{}, "sql"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lookedUp) != 1 || lookedUp[0] != "psql" {
+		t.Fatalf("expected only psql lookup, got %v", lookedUp)
+	}
+}
+
+func TestValidateRestoreToolAvailabilityUsesPgRestoreForCustomDump(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+
+	var lookedUp []string
+	execLookPath = func(file string) (string, error) {
+		lookedUp = append(lookedUp, file)
+		return "/usr/bin/" + file, nil
+	}
+
+	if err := validateRestoreToolAvailability(config.DatabaseConfig{}, "pgdmp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lookedUp) != 1 || lookedUp[0] != "pg_restore" {
+		t.Fatalf("expected only pg_restore lookup, got %v", lookedUp)
+	}
+}
+
+func TestValidateRestoreToolAvailabilityMissingPsql(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+
+	execLookPath = func(file string) (string, error) {
+		if file == "psql" {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+
+	err := validateRestoreToolAvailability(config.DatabaseConfig{}, "sql")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "psql not found in PATH") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPgRestoreArgsSelectiveFilters(t *testing.T) {
+	conn := config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "app"}
+	args := pgRestoreArgs(conn, RestoreOptions{
+		Clean:      true,
+		Schemas:    []string{"billing"},
+		Tables:     []string{"users", " orders "},
+		SchemaOnly: true,
+		UseList:    "toc.list",
+	}, pgArchive{format: "custom"}, false)
+
+	got := strings.Join(args, " ")
+	for _, want := range []string{
+		"--clean --if-exists",
+		"--schema billing",
+		"--table users",
+		"--table orders",
+		"--schema-only",
+		"--use-list toc.list",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in args, got %v", want, args)
+		}
+	}
+	if strings.Contains(got, "--data-only") || strings.Contains(got, "--jobs") {
+		t.Fatalf("unexpected flags in args: %v", args)
+	}
+}
+
+func TestPgRestoreArgsAddsJobs(t *testing.T) {
+	args := pgRestoreArgs(config.ConnectionConfig{Host: "db", Port: 5432}, RestoreOptions{Jobs: 4}, pgArchive{format: "custom"}, false)
+	if !strings.Contains(strings.Join(args, " "), "--jobs 4") {
+		t.Fatalf("expected --jobs 4 in args, got %v", args)
+	}
+}
+
+func TestPgRestoreArgsArchiveFormat(t *testing.T) {
+	args := pgRestoreArgs(config.ConnectionConfig{Host: "db", Port: 5432}, RestoreOptions{Jobs: 4}, pgArchive{format: "directory", path: "/tmp/dump"}, true)
+	got := strings.Join(args, " ")
+	if !strings.Contains(got, "--format=directory") || !strings.HasSuffix(got, "--jobs 4 --verbose /tmp/dump") {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestPostgresRestorerCheckRejectsUnsupportedCombinations(t *testing.T) {
+	orig := execLookPath
+	defer func() { execLookPath = orig }()
+	execLookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	cases := map[string]struct {
+		kind string
+		opts RestoreOptions
+		want string
+	}{
+		"sql without fallback": {"sql", RestoreOptions{}, "--allow-sql-fallback"},
+		"sql with jobs":        {"sql", RestoreOptions{AllowSQLFallback: true, Jobs: 2}, "--jobs requires a pg_dump archive"},
+		"sql selective":        {"sql", RestoreOptions{AllowSQLFallback: true, Tables: []string{"users"}}, "selective restore options"},
+		"tar with jobs":        {"pgtar", RestoreOptions{Jobs: 2}, "not supported for pg_dump tar archives"},
+		"role map with jobs":   {"pgdmp", RestoreOptions{RoleMap: []string{"a=b"}, Jobs: 2}, "cannot be combined with --jobs"},
+		"bad role map":         {"pgdmp", RestoreOptions{RoleMap: []string{"nope"}}, "role-map"},
+		"unknown stream":       {"unknown", RestoreOptions{}, "neither a pg_dump archive"},
+	}
+	for name, tc := range cases {
+		err := PostgresRestorer{}.Check(context.Background(), RestoreRequest{Kind: tc.kind, Options: tc.opts})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", name, tc.want, err)
+		}
+	}
+
+	if err := (PostgresRestorer{}).Check(context.Background(), RestoreRequest{Kind: "dirtar", Options: RestoreOptions{Jobs: 4}}); err != nil {
+		t.Fatalf("directory archive with jobs: %v", err)
+	}
+}
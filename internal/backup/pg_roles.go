@@ -0,0 +1,360 @@
+package backup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// roleRewrite describes ownership and privilege edits applied to a SQL stream
+// before it reaches psql. pg_restore has no way to rename roles, so --role-map
+// always restores through this rewrite.
+type roleRewrite struct {
+	roleMap      map[string]string
+	noOwner      bool
+	noPrivileges bool
+}
+
+var simpleIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// parseRoleMap parses repeated "old=new" pairs.
+func parseRoleMap(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		from, to, ok := strings.Cut(p, "=")
+		from = strings.TrimSpace(from)
+		to = strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid --role-map %q (want old=new)", p)
+		}
+		if _, dup := out[from]; dup {
+			return nil, fmt.Errorf("duplicate --role-map for role %q", from)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+// describeRoleRewrite renders rw for verbose and dry-run output.
+func describeRoleRewrite(rw roleRewrite) string {
+	var parts []string
+	if rw.noOwner {
+		parts = append(parts, "no-owner")
+	}
+	if rw.noPrivileges {
+		parts = append(parts, "no-privileges")
+	}
+	from := make([]string, 0, len(rw.roleMap))
+	for k := range rw.roleMap {
+		from = append(from, k)
+	}
+	sort.Strings(from)
+	for _, k := range from {
+		parts = append(parts, fmt.Sprintf("role %s->%s", k, rw.roleMap[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (rw roleRewrite) active() bool {
+	return len(rw.roleMap) > 0 || rw.noOwner || rw.noPrivileges
+}
+
+// rewriteLine returns the edited statement line and whether it should be kept.
+// Only single-line ownership and privilege statements as emitted by pg_dump are
+// touched; everything else passes through unchanged.
+func (rw roleRewrite) rewriteLine(line string) (string, bool) {
+	upper := strings.ToUpper(line)
+
+	switch {
+	case strings.HasPrefix(upper, "SET SESSION AUTHORIZATION "):
+		if rw.noOwner {
+			return "", false
+		}
+		return rw.mapSessionAuthorization(line), true
+
+	case strings.HasPrefix(upper, "ALTER DEFAULT PRIVILEGES "):
+		if rw.noPrivileges {
+			return "", false
+		}
+		line = rw.mapAfterKeyword(line, " FOR ROLE ")
+		if strings.Contains(upper, " GRANT ") {
+			return rw.mapGrantees(line, " TO "), true
+		}
+		return rw.mapGrantees(line, " FROM "), true
+
+	case strings.HasPrefix(upper, "ALTER ") && strings.Contains(upper, " OWNER TO "):
+		if rw.noOwner {
+			return "", false
+		}
+		return rw.mapAfterKeyword(line, " OWNER TO "), true
+
+	case strings.HasPrefix(upper, "GRANT "):
+		if rw.noPrivileges {
+			return "", false
+		}
+		return rw.mapGrantees(line, " TO "), true
+
+	case strings.HasPrefix(upper, "REVOKE "):
+		if rw.noPrivileges {
+			return "", false
+		}
+		return rw.mapGrantees(line, " FROM "), true
+	}
+
+	return line, true
+}
+
+func (rw roleRewrite) mapRole(ident string) string {
+	name := unquoteIdent(ident)
+	to, ok := rw.roleMap[name]
+	if !ok {
+		return ident
+	}
+	return quoteIdent(to)
+}
+
+// mapAfterKeyword maps the single identifier that follows kw (e.g. " OWNER TO ").
+func (rw roleRewrite) mapAfterKeyword(line, kw string) string {
+	i := strings.Index(strings.ToUpper(line), kw)
+	if i < 0 {
+		return line
+	}
+	start := i + len(kw)
+	end := identEnd(line, start)
+	return line[:start] + rw.mapRole(line[start:end]) + line[end:]
+}
+
+// mapGrantees maps the comma-separated role list after the last kw (" TO " for
+// GRANT, " FROM " for REVOKE), stopping at trailing clauses.
+func (rw roleRewrite) mapGrantees(line, kw string) string {
+	upper := strings.ToUpper(line)
+	i := strings.LastIndex(upper, kw)
+	if i < 0 {
+		return line
+	}
+	start := i + len(kw)
+	end := strings.LastIndex(line, ";")
+	if end < start {
+		end = len(line)
+	}
+	for _, stop := range []string{" WITH ", " GRANTED BY ", " CASCADE", " RESTRICT"} {
+		if j := strings.Index(upper[start:end], stop); j >= 0 {
+			end = start + j
+		}
+	}
+
+	parts := strings.Split(line[start:end], ",")
+	for k, p := range parts {
+		trimmed := strings.TrimSpace(p)
+		if strings.EqualFold(trimmed, "PUBLIC") {
+			continue
+		}
+		parts[k] = strings.Replace(p, trimmed, rw.mapRole(trimmed), 1)
+	}
+	return line[:start] + strings.Join(parts, ",") + line[end:]
+}
+
+func (rw roleRewrite) mapSessionAuthorization(line string) string {
+	open := strings.Index(line, "'")
+	closeQ := strings.LastIndex(line, "'")
+	if open < 0 || closeQ <= open {
+		return line
+	}
+	name := strings.ReplaceAll(line[open+1:closeQ], "''", "'")
+	to, ok := rw.roleMap[name]
+	if !ok {
+		return line
+	}
+	return line[:open+1] + strings.ReplaceAll(to, "'", "''") + line[closeQ:]
+}
+
+// identEnd returns the end offset of the (possibly quoted) identifier at start.
+func identEnd(s string, start int) int {
+	if start < len(s) && s[start] == '"' {
+		for i := start + 1; i < len(s); i++ {
+			if s[i] != '"' {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '"' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+		return len(s)
+	}
+	end := start
+	for end < len(s) && s[end] != ';' && s[end] != ' ' && s[end] != ',' {
+		end++
+	}
+	return end
+}
+
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+	}
+	return s
+}
+
+func quoteIdent(s string) string {
+	if simpleIdent.MatchString(s) {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqlLexState tracks, from line to line, whether the next line of a SQL script
+// starts a new statement or continues a string literal, a dollar-quoted body,
+// a quoted identifier, a block comment or an unterminated statement.
+type sqlLexState struct {
+	quote   bool   // inside '...'
+	escape  bool   // the open literal is an E'...' string with backslash escapes
+	ident   bool   // inside "..."
+	dollar  string // tag of the open dollar quote, e.g. "$$" or "$body$"
+	comment int    // nesting depth of /* */ comments
+	pending bool   // statement text since the last top-level ';'
+}
+
+func (s *sqlLexState) atStatementStart() bool {
+	return !s.quote && !s.ident && s.dollar == "" && s.comment == 0 && !s.pending
+}
+
+// scan advances s over one line.
+func (s *sqlLexState) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case s.comment > 0:
+			if strings.HasPrefix(line[i:], "*/") {
+				s.comment--
+				i++
+			} else if strings.HasPrefix(line[i:], "/*") {
+				s.comment++
+				i++
+			}
+		case s.dollar != "":
+			if strings.HasPrefix(line[i:], s.dollar) {
+				i += len(s.dollar) - 1
+				s.dollar = ""
+			}
+		case s.quote:
+			switch {
+			case s.escape && c == '\\':
+				i++
+			case c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+				i++
+			case c == '\'':
+				s.quote = false
+			}
+		case s.ident:
+			if c == '"' {
+				s.ident = false // a doubled "" reopens on the next byte
+			}
+		case strings.HasPrefix(line[i:], "--"):
+			return
+		case strings.HasPrefix(line[i:], "/*"):
+			s.comment++
+			i++
+		case c == '\'':
+			s.quote, s.pending = true, true
+			s.escape = i > 0 && (line[i-1] == 'E' || line[i-1] == 'e')
+		case c == '"':
+			s.ident, s.pending = true, true
+		case c == '$' && (i == 0 || !isIdentByte(line[i-1])):
+			if tag := dollarTag(line[i:]); tag != "" {
+				s.dollar, s.pending = tag, true
+				i += len(tag) - 1
+			}
+		case c == ';':
+			s.pending = false
+		case c != ' ' && c != '\t' && c != '\r':
+			s.pending = true
+		}
+	}
+}
+
+// dollarTag returns the dollar-quote opener at the start of s ("$$", "$fn$"),
+// or "" when s starts with a positional parameter or a lone '$'.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case !isIdentByte(c) || (i == 1 && c >= '0' && c <= '9'):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// rewriteSQLReader applies rw to each line of src that starts a statement.
+// COPY data blocks, function bodies, multi-line literals and continuation lines
+// of longer statements are passed through untouched.
+func rewriteSQLReader(src io.Reader, rw roleRewrite, closers *CloseStack) io.Reader {
+	pr, pw := io.Pipe()
+	closers.Add(pr)
+
+	go func() {
+		br := bufio.NewReader(src)
+		bw := bufio.NewWriter(pw)
+		inCopy := false
+		var lex sqlLexState
+
+		for {
+			line, readErr := br.ReadString('\n')
+			if line != "" {
+				body := strings.TrimRight(line, "\r\n")
+				eol := line[len(body):]
+
+				keep := true
+				switch {
+				case inCopy:
+					if body == `\.` {
+						inCopy = false
+					}
+				case !lex.atStatementStart():
+					lex.scan(body)
+				case strings.HasPrefix(body, "COPY ") && strings.HasSuffix(body, "FROM stdin;"):
+					inCopy = true
+				default:
+					lex.scan(body)
+					body, keep = rw.rewriteLine(body)
+				}
+
+				if keep {
+					if _, err := bw.WriteString(body + eol); err != nil {
+						_ = pw.CloseWithError(err)
+						return
+					}
+				}
+			}
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			if readErr != nil {
+				_ = pw.CloseWithError(readErr)
+				return
+			}
+		}
+
+		if err := bw.Flush(); err != nil {
+			_ = pw.CloseWithError(err)
+			return
+		}
+		_ = pw.Close()
+	}()
+
+	return pr
+}
@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoleRewriteMapsOwnersAndGrantees(t *testing.T) {
+	rw := roleRewrite{roleMap: map[string]string{"prod_owner": "dev", "Prod-RO": "readonly"}}
+
+	cases := map[string]string{
+		"ALTER TABLE public.prod_owner OWNER TO prod_owner;":                                                   "ALTER TABLE public.prod_owner OWNER TO dev;",
+		`GRANT SELECT ON TABLE public.users TO "Prod-RO";`:                                                     "GRANT SELECT ON TABLE public.users TO readonly;",
+		"GRANT ALL ON SCHEMA prod_owner TO prod_owner, PUBLIC WITH GRANT OPTION;":                              "GRANT ALL ON SCHEMA prod_owner TO dev, PUBLIC WITH GRANT OPTION;",
+		"REVOKE ALL ON SCHEMA public FROM prod_owner;":                                                         "REVOKE ALL ON SCHEMA public FROM dev;",
+		"ALTER DEFAULT PRIVILEGES FOR ROLE prod_owner IN SCHEMA public GRANT SELECT ON TABLES TO \"Prod-RO\";": "ALTER DEFAULT PRIVILEGES FOR ROLE dev IN SCHEMA public GRANT SELECT ON TABLES TO readonly;",
+		"SET SESSION AUTHORIZATION 'prod_owner';":                                                              "SET SESSION AUTHORIZATION 'dev';",
+		"CREATE TABLE public.t (prod_owner text);":                                                             "CREATE TABLE public.t (prod_owner text);",
+	}
+	for in, want := range cases {
+		got, keep := rw.rewriteLine(in)
+		if !keep {
+			t.Fatalf("line unexpectedly dropped: %q", in)
+		}
+		if got != want {
+			t.Fatalf("rewriteLine(%q)\n got  %q\n want %q", in, got, want)
+		}
+	}
+}
+
+func TestRewriteSQLReaderDropsOwnershipAndSkipsCopyData(t *testing.T) {
+	src := strings.Join([]string{
+		"CREATE TABLE public.acl (line text);",
+		"ALTER TABLE public.acl OWNER TO prod;",
+		"COPY public.acl (line) FROM stdin;",
+		"GRANT SELECT ON x TO prod;",
+		`\.`,
+		"GRANT SELECT ON TABLE public.acl TO prod;",
+		"",
+	}, "\n")
+
+	var cs CloseStack
+	r := rewriteSQLReader(strings.NewReader(src), roleRewrite{noOwner: true, noPrivileges: true}, &cs)
+	out, err := io.ReadAll(r)
+	cs.CloseAll()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"CREATE TABLE public.acl (line text);",
+		"COPY public.acl (line) FROM stdin;",
+		"GRANT SELECT ON x TO prod;",
+		`\.`,
+		"",
+	}, "\n")
+	if string(out) != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRewriteSQLReaderLeavesFunctionBodiesAndLiteralsAlone(t *testing.T) {
+	src := strings.Join([]string{
+		"CREATE FUNCTION public.reset_acl() RETURNS void",
+		"    LANGUAGE sql",
+		"    AS $$",
+		"GRANT SELECT ON t TO prod;",
+		"ALTER TABLE x OWNER TO prod;",
+		"$$;",
+		"CREATE FUNCTION public.f() RETURNS void LANGUAGE plpgsql AS $body$",
+		"BEGIN",
+		"  EXECUTE 'x';",
+		"END",
+		"$body$;",
+		"INSERT INTO public.notes VALUES (1, 'first line;",
+		"GRANT ALL ON y TO prod;",
+		"it''s done');",
+		"INSERT INTO public.notes VALUES (2, E'it\\'s",
+		"REVOKE ALL ON y FROM prod;');",
+		"CREATE VIEW public.v AS",
+		" SELECT 1 AS one;",
+		"ALTER FUNCTION public.f() OWNER TO prod;",
+		"GRANT SELECT ON TABLE public.notes TO prod;",
+		"",
+	}, "\n")
+
+	var cs CloseStack
+	r := rewriteSQLReader(strings.NewReader(src), roleRewrite{noOwner: true, noPrivileges: true}, &cs)
+	out, err := io.ReadAll(r)
+	cs.CloseAll()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	want := strings.TrimSuffix(src, "ALTER FUNCTION public.f() OWNER TO prod;\nGRANT SELECT ON TABLE public.notes TO prod;\n")
+	if string(out) != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+
+	r = rewriteSQLReader(strings.NewReader(src), roleRewrite{roleMap: map[string]string{"prod": "dev"}}, &cs)
+	out, err = io.ReadAll(r)
+	cs.CloseAll()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Count(string(out), "TO prod;") != 3 || !strings.Contains(string(out), "ALTER FUNCTION public.f() OWNER TO dev;") {
+		t.Fatalf("role map must only touch statement lines:\n%s", out)
+	}
+}
+
+func TestParseRoleMapRejectsMalformed(t *testing.T) {
+	if _, err := parseRoleMap([]string{"prod"}); err == nil {
+		t.Fatal("expected error for missing '='")
+	}
+	if _, err := parseRoleMap([]string{"a=b", "a=c"}); err == nil {
+		t.Fatal("expected error for duplicate role")
+	}
+	m, err := parseRoleMap([]string{" prod = dev "})
+	if err != nil || m["prod"] != "dev" {
+		t.Fatalf("unexpected result %v, %v", m, err)
+	}
+}
@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// pgTool returns the command for a PostgreSQL client tool: the file in
+// postgres.pg_bin_dir when set, otherwise the bare name looked up in PATH.
+func pgTool(db config.DatabaseConfig, name string) string {
+	if db.Postgres != nil && db.Postgres.PgBinDir != "" {
+		return filepath.Join(db.Postgres.PgBinDir, name)
+	}
+	return name
+}
+
+// lookPgTool resolves pgTool and reports where the tool was expected.
+func lookPgTool(db config.DatabaseConfig, name string) (string, error) {
+	tool := pgTool(db, name)
+	path, err := execLookPath(tool)
+	if err != nil {
+		if tool != name {
+			return "", fmt.Errorf("%s not found in postgres.pg_bin_dir %s: %w", name, db.Postgres.PgBinDir, err)
+		}
+		return "", fmt.Errorf("%s not found in PATH: %w", name, err)
+	}
+	return path, nil
+}
+
+// PgVersions are the versions compared before a Postgres tool runs. Empty
+// fields could not be determined.
+type PgVersions struct {
+	Client string // e.g. "16.2", from <tool> --version
+	Server string // from SHOW server_version
+}
+
+// Versions look like "pg_dump (PostgreSQL) 16.2" and "16.2 (Debian 16.2-1)".
+var (
+	pgToolVersionRe   = regexp.MustCompile(`\(PostgreSQL\) (\d+(?:\.\d+)*)`)
+	pgServerVersionRe = regexp.MustCompile(`^(\d+(?:\.\d+)*)`)
+)
+
+// pgMajor returns the major version: "16" for 16.2, "9.6" for 9.6.24.
+func pgMajor(v string) string {
+	parts := strings.Split(v, ".")
+	if n, err := strconv.Atoi(parts[0]); err == nil && n < 10 && len(parts) > 1 {
+		return parts[0] + "." + parts[1]
+	}
+	return parts[0]
+}
+
+// pgMajorNum orders major versions: 906 for 9.6, 1600 for 16.
+func pgMajorNum(v string) int {
+	parts := strings.Split(pgMajor(v), ".")
+	n, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return n*100 + minor
+}
+
+// pgToolVersion runs <tool> --version.
+func pgToolVersion(ctx context.Context, db config.DatabaseConfig, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, hostProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, pgTool(db, name), "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", name, err)
+	}
+	m := pgToolVersionRe.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("unexpected %s --version output %q", name, strings.TrimSpace(string(out)))
+	}
+	return string(m[1]), nil
+}
+
+// pgServerVersion asks the server for its version with psql.
+func pgServerVersion(ctx context.Context, db config.DatabaseConfig) (string, error) {
+	psql, err := lookPgTool(db, "psql")
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hostProbeTimeout)
+	defer cancel()
+	args := append(pgConnArgs(db.Connection, "--dbname"), "-X", "-A", "-t", "-q", "-c", "SHOW server_version")
+	cmd := exec.CommandContext(ctx, psql, args...)
+	cmd.Env = pgEnv(db.Connection)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("query server version: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	m := pgServerVersionRe.FindString(strings.TrimSpace(stdout.String()))
+	if m == "" {
+		return "", fmt.Errorf("unexpected server_version %q", strings.TrimSpace(stdout.String()))
+	}
+	return m, nil
+}
+
+// CheckPostgresVersions compares the dump tool of db (pg_dump, or pg_basebackup
+// in physical mode) with the server before a backup. Both can only read servers
+// of their own or an older major version. When a version cannot be determined
+// the check is skipped with a warning on w; a failing connection is an error.
+func CheckPostgresVersions(ctx context.Context, db config.DatabaseConfig, w io.Writer) (PgVersions, error) {
+	var v PgVersions
+	if db.Type != "postgres" {
+		return v, nil
+	}
+	tool := "pg_dump"
+	if db.Postgres != nil && db.Postgres.Mode == "physical" {
+		tool = "pg_basebackup"
+	}
+	if _, err := lookPgTool(db, tool); err != nil {
+		return v, nil // the backupper reports the missing tool
+	}
+
+	var err error
+	if v.Client, err = pgToolVersion(ctx, db, tool); err != nil {
+		fmt.Fprintf(w, "warning: version check skipped: db=%s: %v\n", db.Name, err)
+		return v, nil
+	}
+	if _, lookErr := lookPgTool(db, "psql"); lookErr != nil {
+		fmt.Fprintf(w, "warning: version check skipped: db=%s: %v\n", db.Name, lookErr)
+		return v, nil
+	}
+	if v.Server, err = pgServerVersion(ctx, db); err != nil {
+		return v, fmt.Errorf("version check: %w", err)
+	}
+	if pgMajorNum(v.Client) < pgMajorNum(v.Server) {
+		return v, fmt.Errorf(
+			"%s %s is older than the PostgreSQL %s server and cannot back it up; install the PostgreSQL %s client tools or point postgres.pg_bin_dir at them",
+			tool, v.Client, v.Server, pgMajor(v.Server),
+		)
+	}
+	return v, nil
+}
+
+// checkArchiveVersion fails when the archive was written by a newer pg_dump
+// than pg_restore, which would only report "unsupported version in file header".
+// dumpVersion is "" when it could not be read; the check is then skipped, and
+// it is skipped with a warning on w when pg_restore's version is unknown.
+func checkArchiveVersion(ctx context.Context, db config.DatabaseConfig, dumpVersion string, w io.Writer) error {
+	if dumpVersion == "" {
+		return nil
+	}
+	client, err := pgToolVersion(ctx, db, "pg_restore")
+	if err != nil {
+		fmt.Fprintf(w, "warning: archive version check skipped: db=%s: %v\n", db.Name, err)
+		return nil
+	}
+	if pgMajorNum(client) < pgMajorNum(dumpVersion) {
+		return fmt.Errorf(
+			"restore: the archive was written by pg_dump %s, which pg_restore %s cannot read; install the PostgreSQL %s client tools or point postgres.pg_bin_dir at them",
+			dumpVersion, client, pgMajor(dumpVersion),
+		)
+	}
+	return nil
+}
+
+// checkRestoreServerVersion compares pg_restore with the target server before
+// anything is restored, like CheckPostgresVersions does for pg_dump. It
+// connects to the server, so it runs from Restore rather than Check, which
+// also serves --dry-run. Unknown versions skip the check with a warning on w.
+func checkRestoreServerVersion(ctx context.Context, db config.DatabaseConfig, w io.Writer) (PgVersions, error) {
+	var v PgVersions
+	var err error
+	if v.Client, err = pgToolVersion(ctx, db, "pg_restore"); err != nil {
+		fmt.Fprintf(w, "warning: version check skipped: db=%s: %v\n", db.Name, err)
+		return v, nil
+	}
+	if _, lookErr := lookPgTool(db, "psql"); lookErr != nil {
+		fmt.Fprintf(w, "warning: version check skipped: db=%s: %v\n", db.Name, lookErr)
+		return v, nil
+	}
+	if v.Server, err = pgServerVersion(ctx, db); err != nil {
+		return v, fmt.Errorf("restore: version check: %w", err)
+	}
+	if pgMajorNum(v.Client) < pgMajorNum(v.Server) {
+		return v, fmt.Errorf(
+			"restore: pg_restore %s is older than the PostgreSQL %s server; install the PostgreSQL %s client tools or point postgres.pg_bin_dir at them",
+			v.Client, v.Server, pgMajor(v.Server),
+		)
+	}
+	return v, nil
+}
+
+// peekArchiveDumpVersion reads the pg_dump version of a custom or tar archive
+// at the head of stream without consuming it; "" when stream cannot be peeked.
+func peekArchiveDumpVersion(kind string, stream io.Reader) string {
+	p, ok := stream.(interface{ Peek(int) ([]byte, error) })
+	if !ok {
+		return ""
+	}
+	h, _ := p.Peek(1024)
+	switch kind {
+	case "pgdmp":
+		return pgArchiveDumpVersion(h)
+	case "pgtar":
+		// toc.dat is the first tar member; its data follows the 512-byte header.
+		if len(h) > 512 {
+			return pgArchiveDumpVersion(h[512:])
+		}
+	}
+	return ""
+}
+
+// tocDumpVersion reads the pg_dump version from an extracted directory dump.
+func tocDumpVersion(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "toc.dat"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := make([]byte, 1024)
+	n, _ := io.ReadFull(f, h)
+	return pgArchiveDumpVersion(h[:n])
+}
+
+// pgArchiveDumpVersion reads the pg_dump version from the header of a custom
+// archive or a toc.dat (archive version 1.10 and later, pg_dump 8.4+). It
+// returns "" when the header cannot be parsed.
+func pgArchiveDumpVersion(h []byte) string {
+	const fixed = 5 + 3 + 3 // magic, version, intSize/offSize/format
+	if len(h) < fixed || !bytes.HasPrefix(h, pgdmpMagicBytes) {
+		return ""
+	}
+	vmaj, vmin := int(h[5]), int(h[6])
+	version := vmaj*100 + vmin
+	intSize := int(h[8])
+	if version < 110 || intSize < 1 || intSize > 8 {
+		return ""
+	}
+	r := &archiveHeader{b: h[fixed:], intSize: intSize}
+	if version >= 115 {
+		r.skip(1) // compression algorithm
+	} else {
+		r.readInt() // compression level
+	}
+	for i := 0; i < 7; i++ {
+		r.readInt() // creation time
+	}
+	r.readStr() // database name
+	r.readStr() // server version
+	if v := r.readStr(); r.ok {
+		return pgServerVersionRe.FindString(v)
+	}
+	return ""
+}
+
+var pgdmpMagicBytes = []byte("PGDMP")
+
+// archiveHeader reads pg_dump's header encoding: ints are a sign byte followed
+// by intSize little-endian bytes, strings an int length followed by the bytes.
+type archiveHeader struct {
+	b       []byte
+	intSize int
+	ok      bool
+}
+
+func (r *archiveHeader) skip(n int) {
+	if len(r.b) < n {
+		r.b, r.ok = nil, false
+		return
+	}
+	r.b, r.ok = r.b[n:], true
+}
+
+func (r *archiveHeader) readInt() int {
+	if len(r.b) < 1+r.intSize {
+		r.b, r.ok = nil, false
+		return 0
+	}
+	var buf [8]byte
+	copy(buf[:], r.b[1:1+r.intSize])
+	n := int(binary.LittleEndian.Uint64(buf[:]))
+	if r.b[0] != 0 {
+		n = -n
+	}
+	r.b, r.ok = r.b[1+r.intSize:], true
+	return n
+}
+
+func (r *archiveHeader) readStr() string {
+	n := r.readInt()
+	if !r.ok || n < 0 {
+		return ""
+	}
+	if n > len(r.b) {
+		r.b, r.ok = nil, false
+		return ""
+	}
+	s := string(r.b[:n])
+	r.b = r.b[n:]
+	return s
+}
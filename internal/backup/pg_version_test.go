@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// customArchiveHeader builds the head of a custom-format archive with archive
+// version 1.vmin, as written by pg_dump dumpVersion.
+func customArchiveHeader(vmin byte, dumpVersion string) []byte {
+	var b bytes.Buffer
+	b.WriteString("PGDMP")
+	b.Write([]byte{1, vmin, 0, 4, 8, 1})
+	writeInt := func(n int) {
+		b.WriteByte(0)
+		_ = binary.Write(&b, binary.LittleEndian, uint32(n))
+	}
+	writeStr := func(s string) {
+		writeInt(len(s))
+		b.WriteString(s)
+	}
+	if vmin >= 15 {
+		b.WriteByte(1) // gzip
+	} else {
+		writeInt(-1)
+	}
+	for _, n := range []int{5, 4, 3, 2, 1, 124, 0} {
+		writeInt(n)
+	}
+	writeStr("app")
+	writeStr("16.2 (Debian 16.2-1.pgdg120+2)")
+	writeStr(dumpVersion)
+	b.WriteString("rest of the archive")
+	return b.Bytes()
+}
+
+func TestPgArchiveDumpVersion(t *testing.T) {
+	if got := pgArchiveDumpVersion(customArchiveHeader(14, "15.6")); got != "15.6" {
+		t.Fatalf("archive 1.14: got %q", got)
+	}
+	if got := pgArchiveDumpVersion(customArchiveHeader(16, "17.0 (Debian 17.0-1)")); got != "17.0" {
+		t.Fatalf("archive 1.16: got %q", got)
+	}
+	if got := pgArchiveDumpVersion(customArchiveHeader(16, "17.0")[:40]); got != "" {
+		t.Fatalf("truncated header: got %q", got)
+	}
+	if got := pgArchiveDumpVersion([]byte("PGDMP-archive")); got != "" {
+		t.Fatalf("garbage header: got %q", got)
+	}
+	for v, want := range map[string]int{"9.6.24": 906, "10.23": 1000, "16.2": 1600, "17": 1700} {
+		if got := pgMajorNum(v); got != want {
+			t.Fatalf("pgMajorNum(%s) = %d, want %d", v, got, want)
+		}
+	}
+}
+
+func TestPostgresRestorerCheckRejectsNewerArchive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho 'pg_restore (PostgreSQL) 16.4'\n"
+	if err := os.WriteFile(filepath.Join(bin, "pg_restore"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	req := RestoreRequest{
+		DB: config.DatabaseConfig{
+			Name:     "app",
+			Type:     "postgres",
+			Postgres: &config.PostgresConfig{PgBinDir: bin},
+		},
+		Kind:   "pgdmp",
+		Stream: bufio.NewReader(bytes.NewReader(customArchiveHeader(16, "17.2"))),
+	}
+	err := PostgresRestorer{}.Check(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "written by pg_dump 17.2, which pg_restore 16.4 cannot read") {
+		t.Fatalf("expected archive version error, got %v", err)
+	}
+
+	req.Stream = bufio.NewReader(bytes.NewReader(customArchiveHeader(14, "16.1")))
+	if err := (PostgresRestorer{}).Check(context.Background(), req); err != nil {
+		t.Fatalf("expected an older archive to pass, got %v", err)
+	}
+}
+
+func TestCheckRestoreServerVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	bin := t.TempDir()
+	writeTool := func(name, script string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTool("pg_restore", "echo 'pg_restore (PostgreSQL) 15.8'\n")
+	writeTool("psql", "echo '16.2 (Debian 16.2-1.pgdg120+2)'\n")
+	db := config.DatabaseConfig{
+		Name:       "app",
+		Type:       "postgres",
+		Connection: config.ConnectionConfig{Host: "db", Database: "app", User: "postgres"},
+		Postgres:   &config.PostgresConfig{PgBinDir: bin},
+	}
+
+	var warnings bytes.Buffer
+	_, err := checkRestoreServerVersion(context.Background(), db, &warnings)
+	if err == nil || !strings.Contains(err.Error(), "pg_restore 15.8 is older than the PostgreSQL 16.2 server") {
+		t.Fatalf("expected server version error, got %v", err)
+	}
+
+	writeTool("pg_restore", "echo 'pg_restore (PostgreSQL) 16.4'\n")
+	v, err := checkRestoreServerVersion(context.Background(), db, &warnings)
+	if err != nil || v.Client != "16.4" || v.Server != "16.2" {
+		t.Fatalf("expected matching versions to pass, got %+v, %v", v, err)
+	}
+
+	// A canceled restore does not wait for the check; it is skipped with a warning.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := checkArchiveVersion(ctx, db, "16.1", &warnings); err != nil {
+		t.Fatalf("expected the check to be skipped, got %v", err)
+	}
+	if !strings.Contains(warnings.String(), "warning: archive version check skipped: db=app") {
+		t.Fatalf("expected a skipped-check warning, got %q", warnings.String())
+	}
+}
@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// tocTypes are the multi-word pg_restore TOC entry types; anything else is a single word.
+var tocTypes = []string{
+	"MATERIALIZED VIEW DATA",
+	"MATERIALIZED VIEW",
+	"SEQUENCE OWNED BY",
+	"SEQUENCE SET",
+	"TABLE DATA",
+	"FK CONSTRAINT",
+	"DEFAULT ACL",
+	"LARGE OBJECT",
+	"BLOB METADATA",
+	"TEXT SEARCH CONFIGURATION",
+	"TEXT SEARCH DICTIONARY",
+	"EVENT TRIGGER",
+	"FOREIGN TABLE",
+	"SERVER",
+	"USER MAPPING",
+	"PUBLICATION TABLE",
+	"ROW SECURITY",
+}
+
+type tocCount struct {
+	kind string
+	n    int
+}
+
+// summarizeTOC counts pg_restore --list entries by object type, most frequent first.
+func summarizeTOC(r io.Reader) (int, []tocCount) {
+	byKind := make(map[string]int)
+	total := 0
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		kind, ok := tocEntryType(sc.Text())
+		if !ok {
+			continue
+		}
+		byKind[kind]++
+		total++
+	}
+
+	counts := make([]tocCount, 0, len(byKind))
+	for k, n := range byKind {
+		counts = append(counts, tocCount{kind: k, n: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].n != counts[j].n {
+			return counts[i].n > counts[j].n
+		}
+		return counts[i].kind < counts[j].kind
+	})
+	return total, counts
+}
+
+// tocEntryType extracts the object type from a TOC line such as
+// "215; 1259 16386 TABLE public users postgres".
+func tocEntryType(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, ";") {
+		return "", false
+	}
+	semi := strings.Index(line, ";")
+	if semi <= 0 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(line[:semi]); err != nil {
+		return "", false
+	}
+
+	// skip "<catalog oid> <object oid>"
+	fields := strings.Fields(line[semi+1:])
+	if len(fields) < 3 {
+		return "", false
+	}
+	rest := strings.Join(fields[2:], " ")
+	for _, t := range tocTypes {
+		if rest == t || strings.HasPrefix(rest, t+" ") {
+			return t, true
+		}
+	}
+	return fields[2], true
+}
+
+// redactedTarget renders the connection as a URI without the password.
+func redactedTarget(scheme string, conn config.ConnectionConfig) string {
+	u := url.URL{
+		Scheme: scheme,
+		Host:   conn.Host + ":" + strconv.Itoa(conn.Port),
+		Path:   "/" + conn.Database,
+	}
+	if conn.Password != "" {
+		u.User = url.UserPassword(conn.User, "REDACTED")
+	} else {
+		u.User = url.User(conn.User)
+	}
+	return u.String()
+}
+
+// shellJoin quotes args for display so the printed command can be copied into a shell.
+func shellJoin(args []string) string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		switch {
+		case a == "":
+			out[i] = "''"
+		case strings.HasPrefix(a, "<") && strings.HasSuffix(a, ">"):
+			// placeholder such as <temp-file>
+			out[i] = a
+		case !strings.ContainsAny(a, " \t\n'\"\\$`*?;&|<>()"):
+			out[i] = a
+		default:
+			out[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(out, " ")
+}
@@ -1,13 +1,18 @@
 package backup
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/dev-tams/backupkit/internal/config"
 )
@@ -15,29 +20,20 @@ import (
 type PostgresBackupper struct{}
 
 // Backup streams a pg_dump custom-format archive for the given database config.
+// With postgres.jobs above 1 it streams a directory-format dump as a tar instead.
 func (backup PostgresBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
 
-	if _, err := exec.LookPath("pg_dump"); err != nil {
-		return nil, fmt.Errorf(" pg_dump not found in PATH: %w", err)
-	}
-	conn := cfg.Connection
-
-	cmd := exec.CommandContext(
-		ctx,
-		"pg_dump",
-		"--host", conn.Host,
-		"--port", strconv.Itoa(conn.Port),
-		"--dbname", conn.Database,
-		"--username", conn.User,
-		"--format=custom",
-	)
-	// pg_dump reads the password from the environment variable if provided.
-	if conn.Password != "" {
-		cmd.Env = append(os.Environ(), "PGPASSWORD="+conn.Password)
-	} else {
-		cmd.Env = os.Environ()
+	pgDump, err := lookPgTool(cfg, "pg_dump")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Postgres != nil && cfg.Postgres.Jobs > 1 {
+		return backupDirectoryDump(ctx, cfg, pgDump)
 	}
 
+	cmd := exec.CommandContext(ctx, pgDump, pgDumpArgs(cfg, "")...)
+	cmd.Env = pgEnv(cfg.Connection)
+
 	// StdoutPipe returns a reader for the backup stream; call Start before reading.
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -56,3 +52,139 @@ func (backup PostgresBackupper) Backup(ctx context.Context, cfg config.DatabaseC
 	}()
 	return pr, nil
 }
+
+// backupDirectoryDump runs pg_dump -Fd with postgres.jobs workers into a temp
+// directory under $TMPDIR, then streams the directory as a tar. pg_dump can
+// only dump in parallel to a directory. The temp directory is removed when
+// pg_dump fails or once the stream ends, including when the reader closes early.
+func backupDirectoryDump(ctx context.Context, cfg config.DatabaseConfig, pgDump string) (io.ReadCloser, error) {
+	tmp, err := os.MkdirTemp("", "backupkit-pgdump-")
+	if err != nil {
+		return nil, fmt.Errorf("pg_dump/temp: %w", err)
+	}
+	dir := filepath.Join(tmp, "dump")
+
+	cmd := exec.CommandContext(ctx, pgDump, pgDumpArgs(cfg, dir)...)
+	cmd.Env = pgEnv(cfg.Connection)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.RemoveAll(tmp)
+		return nil, fmt.Errorf("pg_dump failed: %w : %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := tarDirectory(pw, dir)
+		// Clean up before closing, so temp space is free when the reader sees EOF.
+		_ = os.RemoveAll(tmp)
+		_ = pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// tarDirectory writes the regular files under dir to w, named "dump/<rel>";
+// restore finds the dump by its toc.dat.
+func tarDirectory(w io.Writer, dir string) error {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pg_dump/tar: %w", err)
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<20)
+	tw := tar.NewWriter(bw)
+	for _, p := range files {
+		if err := tarFile(tw, dir, p); err != nil {
+			return fmt.Errorf("pg_dump/tar: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("pg_dump/tar: %w", err)
+	}
+	return bw.Flush()
+}
+
+func tarFile(tw *tar.Writer, dir, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = "dump/" + filepath.ToSlash(rel)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// pgDumpArgs builds the pg_dump argv from the connection and the postgres options.
+// Patterns are passed with "=" so one starting with a dash is not read as a flag.
+// A non-empty dir selects a parallel directory-format dump into dir.
+func pgDumpArgs(cfg config.DatabaseConfig, dir string) []string {
+	args := pgConnArgs(cfg.Connection, "--dbname")
+
+	opts := config.PostgresConfig{}
+	if cfg.Postgres != nil {
+		opts = *cfg.Postgres
+	}
+	if dir != "" {
+		args = append(args, "--format=directory", "--jobs="+strconv.Itoa(opts.Jobs), "--file="+dir)
+	} else {
+		args = append(args, "--format=custom")
+	}
+	for _, p := range opts.Schemas {
+		args = append(args, "--schema="+p)
+	}
+	for _, p := range opts.ExcludeSchemas {
+		args = append(args, "--exclude-schema="+p)
+	}
+	for _, p := range opts.Tables {
+		args = append(args, "--table="+p)
+	}
+	for _, p := range opts.ExcludeTables {
+		args = append(args, "--exclude-table="+p)
+	}
+	for _, p := range opts.ExcludeTableData {
+		args = append(args, "--exclude-table-data="+p)
+	}
+	if opts.NoOwner {
+		args = append(args, "--no-owner")
+	}
+	if opts.NoPrivileges {
+		args = append(args, "--no-privileges")
+	}
+	if opts.Blobs != nil {
+		if *opts.Blobs {
+			args = append(args, "--blobs")
+		} else {
+			args = append(args, "--no-blobs")
+		}
+	}
+	// Validate rejects bad durations; pg_dump takes plain numbers as milliseconds.
+	if d, _ := opts.LockWait(); d > 0 {
+		args = append(args, "--lock-wait-timeout="+strconv.FormatInt(d.Milliseconds(), 10))
+	}
+	return args
+}
@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func TestPgDumpArgsPassesPostgresOptions(t *testing.T) {
+	conn := config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "backup"}
+	base := "--host db --port 5432 --dbname app --username backup --format=custom"
+
+	if got := strings.Join(pgDumpArgs(config.DatabaseConfig{Connection: conn}, ""), " "); got != base {
+		t.Fatalf("unexpected default pg_dump args:\n got %s\nwant %s", got, base)
+	}
+
+	blobs := false
+	cfg := config.DatabaseConfig{
+		Connection: conn,
+		Postgres: &config.PostgresConfig{
+			Schemas:          []string{"public", "billing"},
+			ExcludeSchemas:   []string{"scratch_*"},
+			Tables:           []string{"public.orders"},
+			ExcludeTables:    []string{"-tmp"},
+			ExcludeTableData: []string{"audit.log_*"},
+			NoOwner:          true,
+			NoPrivileges:     true,
+			Blobs:            &blobs,
+			LockWaitTimeout:  "1m30s",
+		},
+	}
+	want := base + " --schema=public --schema=billing --exclude-schema=scratch_* --table=public.orders" +
+		" --exclude-table=-tmp --exclude-table-data=audit.log_* --no-owner --no-privileges --no-blobs" +
+		" --lock-wait-timeout=90000"
+	if got := strings.Join(pgDumpArgs(cfg, ""), " "); got != want {
+		t.Fatalf("unexpected pg_dump args:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestPgDumpArgsDirectoryFormat(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Type:       "postgres",
+		Connection: config.ConnectionConfig{Host: "db", Port: 5432, Database: "app", User: "backup"},
+		Postgres:   &config.PostgresConfig{Jobs: 4, NoOwner: true},
+	}
+	want := "--host db --port 5432 --dbname app --username backup --format=directory --jobs=4 --file=/tmp/x/dump --no-owner"
+	if got := strings.Join(pgDumpArgs(cfg, "/tmp/x/dump"), " "); got != want {
+		t.Fatalf("unexpected pg_dump args:\n got %s\nwant %s", got, want)
+	}
+
+	e, _ := EngineFor(cfg)
+	if e.Ext != ".tar" || e.Header != "tar" {
+		t.Fatalf("expected a tar engine for jobs > 1, got ext=%q header=%q", e.Ext, e.Header)
+	}
+	if e, _ := Lookup("postgres"); e.Ext != ".dump" {
+		t.Fatalf("EngineFor must not change the registered engine, got ext=%q", e.Ext)
+	}
+}
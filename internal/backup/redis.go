@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("redis", Engine{
+		Backupper: RedisBackupper{},
+		Restorer:  RedisRestorer{},
+		Ext:       ".rdb",
+		Header:    "rdb",
+	})
+}
+
+type RedisBackupper struct{}
+
+// Backup streams an RDB snapshot with redis-cli --rdb, which asks the server for
+// a replication SYNC and writes the transferred snapshot to stdout.
+func (backup RedisBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	if _, err := execLookPath("redis-cli"); err != nil {
+		return nil, fmt.Errorf("redis-cli not found in PATH: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "redis-cli", redisCLIArgs(cfg.Connection)...)
+	cmd.Env = redisEnv(cfg.Connection)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+
+	go func() {
+		err := cmd.Run()
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("redis-cli --rdb failed: %w : %s", err, stderr.String()))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr, nil
+}
+
+func redisCLIArgs(conn config.ConnectionConfig) []string {
+	args := []string{"-h", conn.Host, "-p", strconv.Itoa(conn.Port)}
+	if conn.User != "" {
+		args = append(args, "--user", conn.User)
+	}
+	return append(args, "--rdb", "-")
+}
+
+// redisEnv passes the password via REDISCLI_AUTH so it never shows up in argv.
+func redisEnv(conn config.ConnectionConfig) []string {
+	if conn.Password != "" {
+		return append(os.Environ(), "REDISCLI_AUTH="+conn.Password)
+	}
+	return os.Environ()
+}
+
+// RedisRestorer writes the RDB snapshot to a file. Loading it means replacing
+// the server's dbfilename while Redis is stopped, which is left to the operator.
+type RedisRestorer struct{}
+
+func (RedisRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for redis databases", strings.Join(set, ", "))
+	}
+	if req.Kind != "rdb" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a Redis RDB snapshot (got %s)", req.Kind)
+	}
+	out := RedisRestorer{}.OutputPath(req)
+	if _, err := os.Stat(out); err == nil && !req.Options.Clean {
+		return fmt.Errorf("restore: %s already exists; rerun with --clean to overwrite it", out)
+	}
+	return nil
+}
+
+func (RedisRestorer) Target(db config.DatabaseConfig) string {
+	u := url.URL{Scheme: "redis", Host: db.Connection.Host + ":" + strconv.Itoa(db.Connection.Port)}
+	if db.Connection.User != "" {
+		u.User = url.User(db.Connection.User)
+	}
+	return u.String()
+}
+
+// OutputPath defaults to <db name>.rdb in the working directory.
+func (RedisRestorer) OutputPath(req RestoreRequest) string {
+	if req.Options.OutPath != "" {
+		return req.Options.OutPath
+	}
+	return req.DB.Name + ".rdb"
+}
+
+func (RedisRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	fmt.Fprintln(w, "  load:     manual; stop redis, replace its dbfilename with the RDB file, start redis")
+	if req.Options.Clean {
+		fmt.Fprintln(w, "  clean:    an existing output file is overwritten")
+	}
+	return nil
+}
+
+func (r RedisRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	out := r.OutputPath(req)
+	tmp, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("restore/redis/temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	_, copyErr := io.Copy(tmp, req.Stream)
+	if copyErr == nil {
+		copyErr = tmp.Sync()
+	}
+	if err := tmp.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return fmt.Errorf("restore/stream: %w", copyErr)
+	}
+	if err := os.Rename(tmpPath, out); err != nil {
+		return fmt.Errorf("restore/redis/rename: %w", err)
+	}
+	committed = true
+
+	fmt.Fprintf(req.Out, "restore OK: db=%s from=%s rdb=%s\n", req.DB.Name, req.From, out)
+	fmt.Fprintf(req.Out, "to load it: stop redis, copy %s over the server's dbfilename (CONFIG GET dir / dbfilename), disable appendonly or remove its AOF, then start redis\n", out)
+	return nil
+}
@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// Engine bundles what backupkit needs to back up and restore one database type.
+type Engine struct {
+	Backupper Backupper
+	Restorer  Restorer
+	// Ext is the file extension of an unencoded backup, e.g. ".dump" or ".sql".
+	Ext string
+	// Header is how restore sniffs an unencoded backup: "pgdmp" for pg_dump custom
+	// archives, "unknown" for formats without a recognizable magic (SQL text), or
+	// empty when the format is opaque and not checked.
+	Header string
+	// Variant, when set, adapts the engine to a database's type-specific
+	// settings, e.g. another backup tool or file format.
+	Variant func(e Engine, db config.DatabaseConfig) Engine
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Engine{}
+)
+
+// Register makes an engine available for databases with type dbType. It is
+// meant to be called from init; registering a type twice panics.
+func Register(dbType string, e Engine) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[dbType]; dup {
+		panic("backup: engine registered twice for type " + dbType)
+	}
+	registry[dbType] = e
+}
+
+func Lookup(dbType string) (Engine, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	e, ok := registry[dbType]
+	return e, ok
+}
+
+// EngineFor returns the engine for a configured database, adapted by its
+// Variant.
+func EngineFor(db config.DatabaseConfig) (Engine, bool) {
+	e, ok := Lookup(db.Type)
+	if ok && e.Variant != nil {
+		e = e.Variant(e, db)
+	}
+	return e, ok
+}
+
+// Types lists the registered database types in sorted order.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for t := range registry {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
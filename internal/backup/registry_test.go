@@ -0,0 +1,33 @@
+package backup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistryHasBuiltinEngines(t *testing.T) {
+	if got := Types(); !reflect.DeepEqual(got, []string{"command", "files", "mongo", "mysql", "postgres", "redis", "sqlite"}) {
+		t.Fatalf("unexpected registered types: %v", got)
+	}
+
+	pg, ok := Lookup("postgres")
+	if !ok || pg.Ext != ".dump" || pg.Header != "pgdmp" {
+		t.Fatalf("unexpected postgres engine: %+v", pg)
+	}
+	if _, ok := pg.Restorer.(Lister); !ok {
+		t.Fatal("expected postgres restorer to list archives")
+	}
+
+	if _, ok := Lookup("oracle"); ok {
+		t.Fatal("expected no engine for unknown type")
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate registration")
+		}
+	}()
+	Register("postgres", Engine{})
+}
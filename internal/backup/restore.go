@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+// execLookPath finds backup and restore client tools; replaced in tests.
+var execLookPath = exec.LookPath
+
+// RestoreOptions are the engine-facing restore settings. Engines reject options
+// they do not support in Check.
+type RestoreOptions struct {
+	Clean            bool
+	AllowSQLFallback bool
+	Verbose          bool
+
+	// Selective restore.
+	Schemas    []string
+	Tables     []string
+	SchemaOnly bool
+	DataOnly   bool
+	// UseList is a TOC list file (as printed by Lister) that limits what is restored.
+	UseList string
+
+	// Ownership handling. RoleMap entries are "old=new" role renames.
+	NoOwner      bool
+	NoPrivileges bool
+	Role         string
+	RoleMap      []string
+
+	// Jobs > 1 restores with parallel workers; TempDir holds any files the engine
+	// has to materialize first.
+	Jobs    int
+	TempDir string
+
+	// OutPath is where a FileRestorer writes the restored file.
+	OutPath string
+
+	// WALFetch is the argv, without %f %p, of a restore_command that reads the
+	// WAL archive; set for physical restores of databases with wal_archive.
+	WALFetch []string
+	// TargetTime stops WAL replay at this timestamp ("2006-01-02 15:04:05+00").
+	TargetTime string
+}
+
+func (o RestoreOptions) selective() bool {
+	return len(o.Schemas) > 0 || len(o.Tables) > 0 || o.SchemaOnly || o.DataOnly || o.UseList != ""
+}
+
+// RestoreRequest is a backup whose encryption and compression have already been
+// reversed by the caller.
+type RestoreRequest struct {
+	DB config.DatabaseConfig
+	// From names the backup in status lines.
+	From string
+	// Kind is the sniffed kind of Stream: pgdmp, pgtar, basetar, dirtar, dirzip,
+	// sqlite, sql or unknown.
+	Kind   string
+	Stream io.Reader
+	// StoredSize is the size of the stored (encoded) backup, 0 if unknown. It is a
+	// lower bound for temp space checks.
+	StoredSize int64
+	Options    RestoreOptions
+	// Progress is notified about phases and the object being restored; may be nil.
+	Progress Progress
+	// Out receives status lines.
+	Out io.Writer
+
+	// Globals is a decoded pg_dumpall --globals-only script applied before the
+	// database restore; nil skips it. Only the postgres engine supports it.
+	Globals     io.Reader
+	GlobalsFrom string
+}
+
+// Progress receives restore progress from an engine.
+type Progress interface {
+	SetPhase(phase string)
+	SetEntry(entry string)
+}
+
+type Restorer interface {
+	// Check rejects unsupported options or stream kinds and verifies client tools
+	// before anything touches the database.
+	Check(ctx context.Context, req RestoreRequest) error
+
+	// Target describes the database a restore writes to, with secrets redacted.
+	Target(db config.DatabaseConfig) string
+
+	// Plan writes the commands Restore would run without connecting to the database.
+	Plan(ctx context.Context, req RestoreRequest, w io.Writer) error
+
+	Restore(ctx context.Context, req RestoreRequest) error
+}
+
+// Lister is implemented by restorers that can print an archive's table of contents.
+type Lister interface {
+	List(ctx context.Context, req RestoreRequest, w io.Writer) error
+}
+
+// FileRestorer is implemented by restorers that write the backup to a file for
+// the operator to load instead of writing to the database server.
+type FileRestorer interface {
+	// OutputPath is the file Restore writes, from OutPath or a default.
+	OutputPath(req RestoreRequest) string
+}
+
+// GlobalsRestorer is implemented by restorers that accept RestoreRequest.Globals;
+// Restore applies them with RestoreGlobals before the database backup.
+type GlobalsRestorer interface {
+	RestoreGlobals(ctx context.Context, req RestoreRequest) error
+}
+
+// WALRestorer is implemented by restorers that can replay archived WAL up to
+// RestoreOptions.TargetTime.
+type WALRestorer interface {
+	// ReplaysWAL reports whether db has a WAL archive, so restore passes WALFetch.
+	ReplaysWAL(db config.DatabaseConfig) bool
+}
+
+// UndoRestorer is implemented by restorers whose undo command, printed after a
+// pre-restore snapshot, needs restore flags beyond --db and --from.
+type UndoRestorer interface {
+	UndoArgs() []string
+}
+
+// CloseStack closes the pipe readers of a backup or restore pipeline in
+// reverse order.
+type CloseStack []io.Closer
+
+func (cs *CloseStack) Add(c io.Closer) {
+	*cs = append(*cs, c)
+}
+
+func (cs CloseStack) CloseAll() {
+	for i := len(cs) - 1; i >= 0; i-- {
+		_ = cs[i].Close()
+	}
+}
+
+// runStdinRestore feeds stream to a restore tool on stdin.
+func runStdinRestore(ctx context.Context, tool string, args, env []string, stream io.Reader, cs *CloseStack, req RestoreRequest) error {
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Env = env
+	tool = filepath.Base(tool)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("restore/%s/stdin: %w", tool, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		_ = stdin.Close()
+		return fmt.Errorf("restore/%s/start: %w", tool, err)
+	}
+
+	_, copyErr := io.Copy(stdin, stream)
+	_ = stdin.Close()
+	cs.CloseAll()
+
+	waitErr := cmd.Wait()
+
+	if copyErr != nil {
+		return fmt.Errorf("restore/stream: %w", copyErr)
+	}
+	if waitErr != nil {
+		return fmt.Errorf("restore/%s/wait: %w: %s", tool, waitErr, strings.TrimSpace(stderr.String()))
+	}
+
+	fmt.Fprintf(req.Out, "restore OK: db=%s from=%s tool=%s\n", req.DB.Name, req.From, tool)
+	return nil
+}
@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// spoolToTempFile writes a decoded archive to a seekable temp file so pg_restore
+// can read it with multiple workers. minFree is a lower bound for the space the
+// archive needs (the stored object size; decoded output is usually larger).
+// The returned cleanup removes the file and is safe to call more than once.
+func spoolToTempFile(src io.Reader, dir string, minFree int64) (string, func(), error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	if free, ok := diskFree(dir); ok && free < minFree {
+		return "", nil, fmt.Errorf(
+			"not enough free space in %s: need at least %d bytes, have %d (choose another --temp-dir)",
+			dir,
+			minFree,
+			free,
+		)
+	}
+
+	f, err := os.CreateTemp(dir, "backupkit-restore-*.dump")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp: %w", err)
+	}
+	path := f.Name()
+	cleanup := func() { _ = os.Remove(path) }
+
+	if _, err := io.Copy(f, src); err != nil {
+		_ = f.Close()
+		cleanup()
+		if errors.Is(err, syscall.ENOSPC) {
+			return "", nil, fmt.Errorf("temp dir %s ran out of space while decoding (choose another --temp-dir): %w", dir, err)
+		}
+		return "", nil, fmt.Errorf("write temp: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("close temp: %w", err)
+	}
+
+	return path, cleanup, nil
+}
@@ -0,0 +1,47 @@
+package backup
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpoolToTempFileWritesAndCleansUp(t *testing.T) {
+	dir := t.TempDir()
+
+	path, cleanup, err := spoolToTempFile(strings.NewReader("PGDMP-archive"), dir, 0)
+	if err != nil {
+		t.Fatalf("spoolToTempFile: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read spooled file: %v", err)
+	}
+	if string(got) != "PGDMP-archive" {
+		t.Fatalf("unexpected spooled content %q", got)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected spooled file to be removed, stat err=%v", err)
+	}
+}
+
+func TestSpoolToTempFileRejectsInsufficientSpace(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := diskFree(dir); !ok {
+		t.Skip("free-space check not supported on this platform")
+	}
+
+	_, _, err := spoolToTempFile(strings.NewReader("x"), dir, math.MaxInt64)
+	if err == nil || !strings.Contains(err.Error(), "not enough free space") {
+		t.Fatalf("expected free-space error, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected no temp files left behind, got %d", len(entries))
+	}
+}
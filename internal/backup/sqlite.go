@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dev-tams/backupkit/internal/config"
+)
+
+func init() {
+	Register("sqlite", Engine{
+		Backupper: SQLiteBackupper{},
+		Restorer:  SQLiteRestorer{},
+		Ext:       ".sqlite",
+		Header:    "sqlite",
+	})
+}
+
+type SQLiteBackupper struct{}
+
+// Backup snapshots the database with VACUUM INTO, which reads inside a single
+// transaction and so is consistent even while the file is being written, then
+// streams the snapshot. The temp file is removed when the stream is closed.
+func (backup SQLiteBackupper) Backup(ctx context.Context, cfg config.DatabaseConfig) (io.ReadCloser, error) {
+	if _, err := execLookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("sqlite3 not found in PATH: %w", err)
+	}
+	src := sqlitePath(cfg)
+	// sqlite3 would silently create an empty database for a missing path.
+	if _, err := os.Stat(src); err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "backupkit-sqlite-")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite/snapshot: %w", err)
+	}
+	snap := filepath.Join(dir, "snapshot.sqlite")
+
+	cmd := exec.CommandContext(ctx, "sqlite3", "-readonly", "-bail", src, "VACUUM INTO "+sqlQuote(snap))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("sqlite3 VACUUM INTO failed: %w : %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	f, err := os.Open(snap)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("sqlite/snapshot: %w", err)
+	}
+	return &removeOnClose{File: f, dir: dir}, nil
+}
+
+// removeOnClose deletes the snapshot directory once the stream is consumed.
+type removeOnClose struct {
+	*os.File
+	dir string
+}
+
+func (r *removeOnClose) Close() error {
+	err := r.File.Close()
+	_ = os.RemoveAll(r.dir)
+	return err
+}
+
+// SQLiteRestorer replaces the database file with the restored snapshot. The
+// snapshot is written and integrity-checked next to the target, then renamed
+// over it, so readers see either the old or the new database.
+type SQLiteRestorer struct{}
+
+func (SQLiteRestorer) Check(ctx context.Context, req RestoreRequest) error {
+	if set := postgresOnlyFlags(req.Options); len(set) > 0 {
+		return fmt.Errorf("restore: %s not supported for sqlite databases", strings.Join(set, ", "))
+	}
+	if req.Kind != "sqlite" {
+		return fmt.Errorf("restore/sniff: decoded stream is not a SQLite database (got %s)", req.Kind)
+	}
+	if _, err := execLookPath("sqlite3"); err != nil {
+		return fmt.Errorf("sqlite3 not found in PATH: %w", err)
+	}
+	return checkSQLiteTarget(sqlitePath(req.DB))
+}
+
+func (SQLiteRestorer) Target(db config.DatabaseConfig) string {
+	return "sqlite://" + sqlitePath(db)
+}
+
+func (SQLiteRestorer) Plan(ctx context.Context, req RestoreRequest, w io.Writer) error {
+	target := sqlitePath(req.DB)
+	fmt.Fprintf(w, "  write:    temp file in %s\n", filepath.Dir(target))
+	fmt.Fprintln(w, "  check:    sqlite3 <temp-file> 'PRAGMA integrity_check'")
+	fmt.Fprintf(w, "  replace:  rename <temp-file> -> %s\n", target)
+	if req.Options.Clean {
+		fmt.Fprintln(w, "  clean:    ignored; the database file is always replaced")
+	}
+	return nil
+}
+
+func (SQLiteRestorer) Restore(ctx context.Context, req RestoreRequest) error {
+	target := sqlitePath(req.DB)
+	if req.Options.Clean {
+		fmt.Fprintln(os.Stderr, "warning: --clean is ignored for sqlite; the database file is always replaced")
+	}
+
+	// The temp file must live in the target directory for the rename to be atomic.
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("restore/sqlite/temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(target); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	_, copyErr := io.Copy(tmp, req.Stream)
+	if copyErr == nil {
+		copyErr = tmp.Sync()
+	}
+	if err := tmp.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return fmt.Errorf("restore/stream: %w", copyErr)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("restore/sqlite/chmod: %w", err)
+	}
+
+	if err := sqliteIntegrityCheck(ctx, tmpPath); err != nil {
+		return err
+	}
+
+	// Re-check right before replacing: a writer may have started since Check.
+	if err := checkSQLiteTarget(target); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("restore/sqlite/rename: %w", err)
+	}
+	committed = true
+	syncDir(dir)
+
+	fmt.Fprintf(req.Out, "restore OK: db=%s from=%s path=%s\n", req.DB.Name, req.From, target)
+	return nil
+}
+
+// checkSQLiteTarget refuses to replace a database with uncheckpointed WAL
+// frames: they belong to the old file and would be lost or misapplied.
+func checkSQLiteTarget(path string) error {
+	if fi, err := os.Stat(path + "-wal"); err == nil && fi.Size() > 0 {
+		return fmt.Errorf("restore: %s-wal is not empty; stop writers to the database (or checkpoint it) before restoring", path)
+	}
+	return nil
+}
+
+func sqliteIntegrityCheck(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "sqlite3", "-readonly", "-bail", path, "PRAGMA integrity_check")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("restore/sqlite/integrity_check: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if got := strings.TrimSpace(string(out)); got != "ok" {
+		return fmt.Errorf("restore/sqlite/integrity_check: restored database is corrupt: %s", got)
+	}
+	return nil
+}
+
+// syncDir makes a rename durable; errors are ignored because not every
+// platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+func sqlitePath(db config.DatabaseConfig) string {
+	if db.SQLite == nil {
+		return ""
+	}
+	return db.SQLite.Path
+}
+
+// sqlQuote renders s as a SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
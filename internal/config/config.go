@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -21,6 +25,143 @@ type DatabaseConfig struct {
 	Connection ConnectionConfig `yaml:"connection"`
 	Backup     BackupConfig     `yaml:"backup"`
 	Retention  RetentionConfig  `yaml:"retention"`
+	// Protected databases require --i-understand or interactive confirmation before a restore writes to them.
+	Protected bool `yaml:"protected"`
+	// SnapshotBeforeRestore backs up the target before any destructive (--clean) restore.
+	SnapshotBeforeRestore bool `yaml:"snapshot_before_restore" mapstructure:"snapshot_before_restore"`
+	// Postgres holds pg_dump options for type: postgres.
+	Postgres *PostgresConfig `yaml:"postgres,omitempty"`
+	// MySQL holds mysqldump options for type: mysql.
+	MySQL *MySQLConfig `yaml:"mysql,omitempty"`
+	// SQLite locates the database file for type: sqlite, which has no connection.
+	SQLite *SQLiteConfig `yaml:"sqlite,omitempty"`
+	// Mongo holds mongodump/mongorestore options for type: mongo.
+	Mongo *MongoConfig `yaml:"mongo,omitempty"`
+	// Files lists the directories archived by type: files.
+	Files *FilesConfig `yaml:"files,omitempty"`
+	// Command runs arbitrary tools for type: command.
+	Command *CommandConfig `yaml:"command,omitempty"`
+	// Hooks run commands around backups and restores of this database.
+	Hooks *HooksConfig `yaml:"hooks,omitempty"`
+}
+
+// HooksConfig holds the per-database hooks. post_backup and post_restore run
+// after every backup or restore that got past its pre hook, whether it
+// succeeded or not, so they can undo what the pre hook did; on_failure runs
+// after any failed backup or restore.
+type HooksConfig struct {
+	PreBackup   *HookConfig `yaml:"pre_backup,omitempty" mapstructure:"pre_backup"`
+	PostBackup  *HookConfig `yaml:"post_backup,omitempty" mapstructure:"post_backup"`
+	OnFailure   *HookConfig `yaml:"on_failure,omitempty" mapstructure:"on_failure"`
+	PreRestore  *HookConfig `yaml:"pre_restore,omitempty" mapstructure:"pre_restore"`
+	PostRestore *HookConfig `yaml:"post_restore,omitempty" mapstructure:"post_restore"`
+}
+
+// DefaultHookTimeout bounds a hook without a timeout.
+const DefaultHookTimeout = 5 * time.Minute
+
+type HookConfig struct {
+	// Command is the argv to run, without a shell; use ["sh", "-c", "..."] for one.
+	Command []string `yaml:"command"`
+	// Timeout is a Go duration such as "30s"; DefaultHookTimeout when empty.
+	Timeout string `yaml:"timeout"`
+	// OnError is "abort" (the default) to fail the run when the hook fails, or
+	// "warn" to log the failure and carry on. on_failure hooks always warn.
+	OnError string `yaml:"on_error" mapstructure:"on_error"`
+}
+
+func (h HookConfig) TimeoutDuration() (time.Duration, error) {
+	if h.Timeout == "" {
+		return DefaultHookTimeout, nil
+	}
+	return time.ParseDuration(h.Timeout)
+}
+
+type SQLiteConfig struct {
+	Path string `yaml:"path"`
+}
+
+type FilesConfig struct {
+	Paths []string `yaml:"paths"`
+	// Include and Exclude are globs matched against paths relative to each entry
+	// in Paths; a pattern without a slash matches the base name at any depth.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// Symlinks is "preserve" (default), "follow" or "skip".
+	Symlinks string `yaml:"symlinks"`
+}
+
+type PostgresConfig struct {
+	// Mode is "logical" (pg_dump, the default) or "physical" (pg_basebackup of
+	// the whole cluster).
+	Mode string `yaml:"mode"`
+	// WALArchive accepts WAL segments from wal-push into <db>/wal/ of the backup
+	// storage for point-in-time recovery. Requires mode physical.
+	WALArchive bool `yaml:"wal_archive" mapstructure:"wal_archive"`
+
+	// Globals also backs up roles and tablespaces with pg_dumpall --globals-only
+	// to <db>/globals/.
+	Globals bool `yaml:"globals"`
+
+	// pg_dump patterns; see --schema, --exclude-schema, --table, --exclude-table
+	// and --exclude-table-data.
+	Schemas          []string `yaml:"schemas"`
+	ExcludeSchemas   []string `yaml:"exclude_schemas" mapstructure:"exclude_schemas"`
+	Tables           []string `yaml:"tables"`
+	ExcludeTables    []string `yaml:"exclude_tables" mapstructure:"exclude_tables"`
+	ExcludeTableData []string `yaml:"exclude_table_data" mapstructure:"exclude_table_data"`
+
+	NoOwner      bool `yaml:"no_owner" mapstructure:"no_owner"`
+	NoPrivileges bool `yaml:"no_privileges" mapstructure:"no_privileges"`
+	// Blobs forces large objects in (true) or out (false); unset keeps pg_dump's
+	// default, which drops them when schemas or tables are selected.
+	Blobs *bool `yaml:"blobs"`
+	// LockWaitTimeout is a Go duration ("30s"); pg_dump fails instead of waiting
+	// longer than this for a table lock.
+	LockWaitTimeout string `yaml:"lock_wait_timeout" mapstructure:"lock_wait_timeout"`
+	// Jobs above 1 dumps with pg_dump -Fd -j N into a temp directory and stores
+	// it as a tar; restore --jobs can then restore in parallel too.
+	Jobs int `yaml:"jobs"`
+
+	// PgBinDir runs pg_dump, pg_restore, psql and the other client tools from
+	// this directory instead of PATH, e.g. /usr/lib/postgresql/16/bin.
+	PgBinDir string `yaml:"pg_bin_dir" mapstructure:"pg_bin_dir"`
+}
+
+// LockWait parses LockWaitTimeout; 0 means no timeout.
+func (p PostgresConfig) LockWait() (time.Duration, error) {
+	if p.LockWaitTimeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(p.LockWaitTimeout)
+}
+
+type CommandConfig struct {
+	// Backup is the argv whose stdout is the backup stream.
+	Backup []string `yaml:"backup"`
+	// Restore is an optional argv that receives the decoded stream on stdin.
+	Restore []string `yaml:"restore"`
+	// Env holds extra KEY=value entries for both commands. It is a list rather
+	// than a map so variable names keep their case.
+	Env []string `yaml:"env"`
+}
+
+type MongoConfig struct {
+	// URI replaces host/port/user/password from connection, e.g. for replica sets or SRV records.
+	URI            string `yaml:"uri"`
+	AuthDB         string `yaml:"auth_db" mapstructure:"auth_db"`
+	ReadPreference string `yaml:"read_preference" mapstructure:"read_preference"`
+}
+
+type MySQLConfig struct {
+	Routines bool `yaml:"routines"`
+	Events   bool `yaml:"events"`
+	// Triggers defaults to true, like mysqldump itself.
+	Triggers *bool `yaml:"triggers"`
+}
+
+func (m MySQLConfig) TriggersEnabled() bool {
+	return m.Triggers == nil || *m.Triggers
 }
 
 type ConnectionConfig struct {
@@ -29,6 +170,50 @@ type ConnectionConfig struct {
 	Database string `yaml:"database"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
+
+	// Postgres only. URI is a postgres:// connection URI used instead of
+	// host/port/database/user; Service names a pg_service.conf entry.
+	URI     string `yaml:"uri"`
+	Service string `yaml:"service"`
+	// libpq TLS and session settings, passed as PGSSLMODE, PGSSLROOTCERT,
+	// PGSSLCERT, PGSSLKEY, PGAPPNAME and PGOPTIONS.
+	SSLMode         string `yaml:"sslmode"`
+	SSLRootCert     string `yaml:"sslrootcert"`
+	SSLCert         string `yaml:"sslcert"`
+	SSLKey          string `yaml:"sslkey"`
+	ApplicationName string `yaml:"application_name" mapstructure:"application_name"`
+	Options         string `yaml:"options"`
+
+	// Hosts replaces Host with an ordered list of "host" or "host:port" entries
+	// (port defaults to Port). Each is probed before a backup and Prefer picks
+	// one: "any" (first usable, the default), "standby" (first usable standby,
+	// else the first primary) or "primary". Restores always use a primary.
+	Hosts  []string `yaml:"hosts"`
+	Prefer string   `yaml:"prefer"`
+	// MaxReplayLag is a Go duration; standbys further behind are not used.
+	MaxReplayLag string `yaml:"max_replay_lag" mapstructure:"max_replay_lag"`
+}
+
+// ReplayLag parses MaxReplayLag; 0 means any lag is accepted.
+func (c ConnectionConfig) ReplayLag() (time.Duration, error) {
+	if c.MaxReplayLag == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.MaxReplayLag)
+}
+
+// SplitHostEntry splits a connection.hosts entry into host and port. Entries
+// without a port get defaultPort; IPv6 addresses with a port need brackets.
+func SplitHostEntry(entry string, defaultPort int) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(entry)
+	if err != nil {
+		return strings.Trim(entry, "[]"), defaultPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("%q: invalid port", entry)
+	}
+	return host, port, nil
 }
 
 type BackupConfig struct {
@@ -116,7 +301,16 @@ func expandEnvVars(cfg *Config) {
 	for i := range cfg.Databases {
 		db := &cfg.Databases[i]
 		db.Connection.Password = os.ExpandEnv(db.Connection.Password)
+		db.Connection.URI = os.ExpandEnv(db.Connection.URI)
 		db.Backup.Encryption.Password = os.ExpandEnv(db.Backup.Encryption.Password)
+		if db.Mongo != nil {
+			db.Mongo.URI = os.ExpandEnv(db.Mongo.URI)
+		}
+		if db.Command != nil {
+			for j, kv := range db.Command.Env {
+				db.Command.Env[j] = os.ExpandEnv(kv)
+			}
+		}
 	}
 
 	for i := range cfg.Storage {
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dev-tams/backupkit/internal/schedule"
 )
@@ -53,9 +57,69 @@ func (c *Config) Validate() error {
 		if db.Type == "" {
 			return fmt.Errorf("databases[%d].type is required (e.g. postgres)", i)
 		}
-		if db.Connection.Host == "" || db.Connection.Port == 0 || db.Connection.Database == "" || db.Connection.User == "" {
+		switch {
+		case db.Type == "sqlite":
+			if db.SQLite == nil || strings.TrimSpace(db.SQLite.Path) == "" {
+				return fmt.Errorf("databases[%d] sqlite.path is required for type sqlite", i)
+			}
+		case db.Type == "mongo":
+			// Without a database every database is dumped; auth is optional.
+			if (db.Mongo == nil || db.Mongo.URI == "") && (db.Connection.Host == "" || db.Connection.Port == 0) {
+				return fmt.Errorf("databases[%d] mongo needs mongo.uri or connection host/port", i)
+			}
+			if db.Mongo != nil && db.Mongo.URI != "" && (db.Connection.Host != "" || db.Connection.Port != 0) {
+				return fmt.Errorf("databases[%d] mongo.uri cannot be combined with connection host/port", i)
+			}
+		case db.Type == "files":
+			if err := validateFiles(db.Files); err != nil {
+				return fmt.Errorf("databases[%d] %w", i, err)
+			}
+		case db.Type == "command":
+			if err := validateCommand(db.Command); err != nil {
+				return fmt.Errorf("databases[%d] %w", i, err)
+			}
+		case db.Type == "redis":
+			if db.Connection.Host == "" || db.Connection.Port == 0 {
+				return fmt.Errorf("databases[%d] connection is incomplete (host/port required)", i)
+			}
+		case db.Type == "postgres":
+			if err := validatePostgresConnection(db.Connection); err != nil {
+				return fmt.Errorf("databases[%d] %w", i, err)
+			}
+		case db.Connection.Host == "" || db.Connection.Port == 0 || db.Connection.Database == "" || db.Connection.User == "":
 			return fmt.Errorf("databases[%d] connection is incomplete (host/port/database/user required)", i)
 		}
+		if db.Type != "postgres" && hasPostgresConnectionOptions(db.Connection) {
+			return fmt.Errorf("databases[%d] connection uri, service, hosts, ssl and session options require type postgres", i)
+		}
+		if db.MySQL != nil && db.Type != "mysql" {
+			return fmt.Errorf("databases[%d] mysql options require type mysql", i)
+		}
+		if db.SQLite != nil && db.Type != "sqlite" {
+			return fmt.Errorf("databases[%d] sqlite options require type sqlite", i)
+		}
+		if db.Mongo != nil && db.Type != "mongo" {
+			return fmt.Errorf("databases[%d] mongo options require type mongo", i)
+		}
+		if db.Files != nil && db.Type != "files" {
+			return fmt.Errorf("databases[%d] files options require type files", i)
+		}
+		if db.Postgres != nil && db.Type != "postgres" {
+			return fmt.Errorf("databases[%d] postgres options require type postgres", i)
+		}
+		if db.Command != nil && db.Type != "command" {
+			return fmt.Errorf("databases[%d] command options require type command", i)
+		}
+		if db.Postgres != nil {
+			if err := validatePostgres(db.Postgres); err != nil {
+				return fmt.Errorf("databases[%d] %w", i, err)
+			}
+		}
+		if db.Hooks != nil {
+			if err := validateHooks(db.Hooks); err != nil {
+				return fmt.Errorf("databases[%d] %w", i, err)
+			}
+		}
 		if db.Backup.Storage == "" {
 			return fmt.Errorf("databases[%d] backup.storage is required (must match a storage.name)", i)
 		}
@@ -128,3 +192,180 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+func validateFiles(f *FilesConfig) error {
+	if f == nil || len(f.Paths) == 0 {
+		return fmt.Errorf("files.paths is required for type files")
+	}
+	for _, p := range f.Paths {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("files.paths must not contain empty entries")
+		}
+	}
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(strings.Trim(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("files pattern %q is invalid: %w", pattern, err)
+		}
+	}
+	switch f.Symlinks {
+	case "", "preserve", "follow", "skip":
+	default:
+		return fmt.Errorf("files.symlinks=%q must be preserve, follow or skip", f.Symlinks)
+	}
+	return nil
+}
+
+var postgresSSLModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
+
+// validatePostgresConnection accepts host/port/database/user, a URI, or a
+// service whose pg_service.conf entry may supply any of them.
+func validatePostgresConnection(c ConnectionConfig) error {
+	switch {
+	case c.URI != "":
+		u, err := url.Parse(c.URI)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			return fmt.Errorf("connection.uri must be a postgres:// or postgresql:// URI")
+		}
+		if c.Host != "" || c.Port != 0 || c.Database != "" || c.User != "" || c.Service != "" || len(c.Hosts) > 0 {
+			return fmt.Errorf("connection.uri cannot be combined with host/port/database/user, hosts or service")
+		}
+		if _, ok := u.User.Password(); ok && c.Password != "" {
+			return fmt.Errorf("connection.password cannot be set when connection.uri contains a password")
+		}
+	case len(c.Hosts) > 0:
+		if c.Host != "" || c.Service != "" {
+			return fmt.Errorf("connection.hosts cannot be combined with connection.host or service")
+		}
+		if c.Database == "" || c.User == "" {
+			return fmt.Errorf("connection is incomplete (database/user required with hosts)")
+		}
+		for _, h := range c.Hosts {
+			if strings.TrimSpace(h) == "" {
+				return fmt.Errorf("connection.hosts must not contain empty entries")
+			}
+			if _, _, err := SplitHostEntry(h, c.Port); err != nil {
+				return fmt.Errorf("connection.hosts: %w", err)
+			}
+		}
+	case c.Service != "":
+	case c.Host == "" || c.Port == 0 || c.Database == "" || c.User == "":
+		return fmt.Errorf("connection is incomplete (host/port/database/user, uri or service required)")
+	}
+	switch c.Prefer {
+	case "", "any", "standby", "primary":
+	default:
+		return fmt.Errorf("connection.prefer=%q must be any, standby or primary", c.Prefer)
+	}
+	if (c.Prefer != "" || c.MaxReplayLag != "") && len(c.Hosts) == 0 {
+		return fmt.Errorf("connection.prefer and connection.max_replay_lag require connection.hosts")
+	}
+	if d, err := c.ReplayLag(); err != nil || d < 0 {
+		return fmt.Errorf("connection.max_replay_lag=%q must be a non-negative Go duration", c.MaxReplayLag)
+	}
+	if c.SSLMode != "" && !postgresSSLModes[c.SSLMode] {
+		return fmt.Errorf("connection.sslmode=%q must be disable, allow, prefer, require, verify-ca or verify-full", c.SSLMode)
+	}
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		return fmt.Errorf("connection.sslcert and connection.sslkey must be set together")
+	}
+	return nil
+}
+
+func hasPostgresConnectionOptions(c ConnectionConfig) bool {
+	return c.URI != "" || c.Service != "" || c.SSLMode != "" || c.SSLRootCert != "" || c.SSLCert != "" ||
+		c.SSLKey != "" || c.ApplicationName != "" || c.Options != "" || len(c.Hosts) > 0 || c.Prefer != "" || c.MaxReplayLag != ""
+}
+
+func validatePostgres(p *PostgresConfig) error {
+	switch p.Mode {
+	case "", "logical":
+	case "physical":
+		// A base backup always holds every database, role and object of the cluster.
+		if p.Globals || len(p.Schemas) > 0 || len(p.ExcludeSchemas) > 0 || len(p.Tables) > 0 ||
+			len(p.ExcludeTables) > 0 || len(p.ExcludeTableData) > 0 || p.NoOwner || p.NoPrivileges || p.Blobs != nil || p.LockWaitTimeout != "" || p.Jobs != 0 {
+			return fmt.Errorf("postgres mode physical backs up the whole cluster; remove globals and pg_dump options")
+		}
+	default:
+		return fmt.Errorf("postgres.mode=%q must be logical or physical", p.Mode)
+	}
+	if p.PgBinDir != "" && !filepath.IsAbs(p.PgBinDir) {
+		return fmt.Errorf("postgres.pg_bin_dir=%q must be an absolute path", p.PgBinDir)
+	}
+	if p.Jobs < 0 {
+		return fmt.Errorf("postgres.jobs=%d must not be negative", p.Jobs)
+	}
+	if p.WALArchive && p.Mode != "physical" {
+		return fmt.Errorf("postgres.wal_archive requires mode physical; WAL can only be replayed on a base backup")
+	}
+	lists := []struct {
+		key      string
+		patterns []string
+	}{
+		{"schemas", p.Schemas},
+		{"exclude_schemas", p.ExcludeSchemas},
+		{"tables", p.Tables},
+		{"exclude_tables", p.ExcludeTables},
+		{"exclude_table_data", p.ExcludeTableData},
+	}
+	for _, l := range lists {
+		for _, pattern := range l.patterns {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("postgres.%s must not contain empty entries", l.key)
+			}
+		}
+	}
+	d, err := p.LockWait()
+	if err != nil {
+		return fmt.Errorf("postgres.lock_wait_timeout=%q is invalid: %w", p.LockWaitTimeout, err)
+	}
+	if d < 0 || (p.LockWaitTimeout != "" && d < time.Millisecond) {
+		return fmt.Errorf("postgres.lock_wait_timeout=%q must be at least 1ms", p.LockWaitTimeout)
+	}
+	return nil
+}
+
+func validateHooks(h *HooksConfig) error {
+	for _, hook := range []struct {
+		name string
+		cfg  *HookConfig
+	}{
+		{"pre_backup", h.PreBackup},
+		{"post_backup", h.PostBackup},
+		{"on_failure", h.OnFailure},
+		{"pre_restore", h.PreRestore},
+		{"post_restore", h.PostRestore},
+	} {
+		if hook.cfg == nil {
+			continue
+		}
+		if len(hook.cfg.Command) == 0 || strings.TrimSpace(hook.cfg.Command[0]) == "" {
+			return fmt.Errorf("hooks.%s.command argv is required", hook.name)
+		}
+		if d, err := hook.cfg.TimeoutDuration(); err != nil || d <= 0 {
+			return fmt.Errorf("hooks.%s.timeout=%q must be a positive Go duration", hook.name, hook.cfg.Timeout)
+		}
+		switch hook.cfg.OnError {
+		case "", "abort", "warn":
+		default:
+			return fmt.Errorf("hooks.%s.on_error=%q must be abort or warn", hook.name, hook.cfg.OnError)
+		}
+	}
+	return nil
+}
+
+func validateCommand(c *CommandConfig) error {
+	if c == nil || len(c.Backup) == 0 || strings.TrimSpace(c.Backup[0]) == "" {
+		return fmt.Errorf("command.backup argv is required for type command")
+	}
+	if len(c.Restore) > 0 && strings.TrimSpace(c.Restore[0]) == "" {
+		return fmt.Errorf("command.restore argv must start with a program")
+	}
+	for _, kv := range c.Env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return fmt.Errorf("command.env entry %q must be KEY=value", kv)
+		}
+	}
+	return nil
+}
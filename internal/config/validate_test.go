@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -167,3 +168,330 @@ func TestValidateRejectsEmailWithHalfCredentials(t *testing.T) {
 		t.Fatalf("expected credentials pair error, got: %v", err)
 	}
 }
+
+func TestValidateRejectsMySQLOptionsOnPostgres(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].MySQL = &MySQLConfig{Routines: true}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "mysql options require type mysql") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.Databases[0].Type = "mysql"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected mysql options to be valid for type mysql, got %v", err)
+	}
+}
+
+func TestValidateSQLiteRequiresPathInsteadOfConnection(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Type = "sqlite"
+	cfg.Databases[0].Connection = ConnectionConfig{}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "sqlite.path is required") {
+		t.Fatalf("expected missing sqlite.path error, got %v", err)
+	}
+
+	cfg.Databases[0].SQLite = &SQLiteConfig{Path: "/var/lib/app/app.db"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected sqlite config to be valid, got %v", err)
+	}
+
+	cfg.Databases[0].Type = "postgres"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for sqlite options on a postgres database")
+	}
+}
+
+func TestValidateMongoAcceptsURIOrHost(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Type = "mongo"
+	cfg.Databases[0].Connection = ConnectionConfig{}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mongo needs mongo.uri") {
+		t.Fatalf("expected missing connection error, got %v", err)
+	}
+
+	cfg.Databases[0].Mongo = &MongoConfig{URI: "mongodb://mongo:27017/events"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected uri-only mongo config to be valid, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{Host: "mongo", Port: 27017}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("expected uri/host conflict, got %v", err)
+	}
+
+	cfg.Databases[0].Mongo = nil
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected host-only mongo config without user to be valid, got %v", err)
+	}
+}
+
+func TestValidateFilesSource(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Type = "files"
+	cfg.Databases[0].Connection = ConnectionConfig{}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "files.paths is required") {
+		t.Fatalf("expected missing files.paths error, got %v", err)
+	}
+
+	cfg.Databases[0].Files = &FilesConfig{Paths: []string{"/srv/site"}, Exclude: []string{"[bad"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "is invalid") {
+		t.Fatalf("expected bad pattern error, got %v", err)
+	}
+
+	cfg.Databases[0].Files = &FilesConfig{Paths: []string{"/srv/site"}, Symlinks: "copy"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "files.symlinks") {
+		t.Fatalf("expected symlinks mode error, got %v", err)
+	}
+
+	cfg.Databases[0].Files = &FilesConfig{Paths: []string{"/srv/site"}, Exclude: []string{"*.log", "/cache/"}, Symlinks: "skip"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid files config, got %v", err)
+	}
+}
+
+func TestValidateCommandSource(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Type = "command"
+	cfg.Databases[0].Connection = ConnectionConfig{}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "command.backup argv is required") {
+		t.Fatalf("expected missing command.backup error, got %v", err)
+	}
+
+	cfg.Databases[0].Command = &CommandConfig{Backup: []string{"etcdctl", "snapshot", "save", "-"}, Env: []string{"ETCDCTL_API"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be KEY=value") {
+		t.Fatalf("expected bad env entry error, got %v", err)
+	}
+
+	cfg.Databases[0].Command.Env = []string{"ETCDCTL_API=3"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid command config, got %v", err)
+	}
+
+	cfg.Databases[0].Type = "postgres"
+	cfg.Databases[0].Connection = baseValidConfig().Databases[0].Connection
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "command options require type command") {
+		t.Fatalf("expected command options rejected for postgres, got %v", err)
+	}
+}
+
+func TestValidatePostgresOptionsRequirePostgres(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Postgres = &PostgresConfig{Globals: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid postgres options, got %v", err)
+	}
+
+	cfg.Databases[0].Type = "mysql"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "postgres options require type postgres") {
+		t.Fatalf("expected postgres options rejected for mysql, got %v", err)
+	}
+}
+
+func TestValidatePostgresDumpOptions(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Postgres = &PostgresConfig{ExcludeTableData: []string{" "}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "postgres.exclude_table_data must not contain empty entries") {
+		t.Fatalf("expected empty pattern error, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{LockWaitTimeout: "30"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "lock_wait_timeout") {
+		t.Fatalf("expected duration error, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Jobs: -1}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "postgres.jobs=-1 must not be negative") {
+		t.Fatalf("expected jobs error, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Mode: "physical", Jobs: 4}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "whole cluster") {
+		t.Fatalf("expected physical mode to reject jobs, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Schemas: []string{"public"}, ExcludeTableData: []string{"audit.*"}, LockWaitTimeout: "30s", Jobs: 4}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid postgres options, got %v", err)
+	}
+}
+
+func TestValidatePostgresMode(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Postgres = &PostgresConfig{Mode: "streaming"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be logical or physical") {
+		t.Fatalf("expected mode error, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Mode: "physical", Globals: true}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "whole cluster") {
+		t.Fatalf("expected physical mode to reject globals, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Mode: "physical"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid physical config, got %v", err)
+	}
+}
+
+func TestValidateWALArchiveRequiresPhysical(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Postgres = &PostgresConfig{WALArchive: true}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "wal_archive requires mode physical") {
+		t.Fatalf("expected wal_archive to require physical mode, got %v", err)
+	}
+	cfg.Databases[0].Postgres.Mode = "physical"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid wal_archive config, got %v", err)
+	}
+}
+
+func TestValidatePostgresConnectionForms(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Connection = ConnectionConfig{URI: "postgresql://app:pw@db.example.com/app?sslmode=verify-full"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid uri connection, got %v", err)
+	}
+
+	cfg.Databases[0].Connection.Host = "db"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "connection.uri cannot be combined") {
+		t.Fatalf("expected uri/host conflict, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{URI: "postgresql://app:pw@db/app", Password: "other"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "uri contains a password") {
+		t.Fatalf("expected duplicate password error, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{URI: "mysql://db/app"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "postgres:// or postgresql://") {
+		t.Fatalf("expected uri scheme error, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{Service: "prod"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid service connection, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{Service: "prod", SSLMode: "verify"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `connection.sslmode="verify"`) {
+		t.Fatalf("expected sslmode error, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{Service: "prod", SSLCert: "client.crt"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "sslcert and connection.sslkey must be set together") {
+		t.Fatalf("expected sslcert/sslkey error, got %v", err)
+	}
+
+	cfg.Databases[0].Connection = ConnectionConfig{}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "connection is incomplete") {
+		t.Fatalf("expected incomplete connection error, got %v", err)
+	}
+}
+
+func TestValidatePostgresConnectionOptionsRequirePostgres(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Type = "mysql"
+	cfg.Databases[0].Connection.SSLMode = "require"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "require type postgres") {
+		t.Fatalf("expected ssl options rejected for mysql, got %v", err)
+	}
+}
+
+func TestValidatePostgresHosts(t *testing.T) {
+	cfg := baseValidConfig()
+	conn := &cfg.Databases[0].Connection
+	conn.Host = ""
+	conn.Hosts = []string{"primary", "replica:6432", "[::1]:5433"}
+	conn.Prefer = "standby"
+	conn.MaxReplayLag = "30s"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid hosts, got %v", err)
+	}
+
+	conn.Hosts = []string{"replica:x"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid port") {
+		t.Fatalf("expected port error, got %v", err)
+	}
+
+	conn.Hosts = []string{"primary"}
+	conn.Prefer = "replica"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be any, standby or primary") {
+		t.Fatalf("expected prefer error, got %v", err)
+	}
+
+	conn.Prefer = ""
+	conn.MaxReplayLag = "soon"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "max_replay_lag") {
+		t.Fatalf("expected max_replay_lag error, got %v", err)
+	}
+
+	conn.MaxReplayLag = ""
+	conn.Host = "db"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "hosts cannot be combined") {
+		t.Fatalf("expected host/hosts conflict, got %v", err)
+	}
+
+	conn.Hosts = nil
+	conn.Prefer = "standby"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "require connection.hosts") {
+		t.Fatalf("expected prefer without hosts error, got %v", err)
+	}
+}
+
+func TestSplitHostEntry(t *testing.T) {
+	for entry, want := range map[string]string{"db": "db:5432", "db:6432": "db:6432", "[::1]:6432": "::1:6432", "::1": "::1:5432"} {
+		host, port, err := SplitHostEntry(entry, 5432)
+		if err != nil || fmt.Sprintf("%s:%d", host, port) != want {
+			t.Fatalf("SplitHostEntry(%q) = %s, %d, %v; want %s", entry, host, port, err, want)
+		}
+	}
+}
+
+func TestValidatePgBinDir(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Databases[0].Postgres = &PostgresConfig{PgBinDir: "bin"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "pg_bin_dir") {
+		t.Fatalf("expected relative pg_bin_dir error, got %v", err)
+	}
+
+	cfg.Databases[0].Postgres = &PostgresConfig{Mode: "physical", PgBinDir: "/usr/lib/postgresql/16/bin"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected pg_bin_dir to be valid in physical mode, got %v", err)
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	cases := []struct {
+		hook HookConfig
+		want string
+	}{
+		{HookConfig{Command: []string{"sh", "-c", "true"}, Timeout: "30s", OnError: "warn"}, ""},
+		{HookConfig{Command: []string{"true"}}, ""},
+		{HookConfig{}, "hooks.post_restore.command"},
+		{HookConfig{Command: []string{"true"}, Timeout: "soon"}, "hooks.post_restore.timeout"},
+		{HookConfig{Command: []string{"true"}, Timeout: "0s"}, "hooks.post_restore.timeout"},
+		{HookConfig{Command: []string{"true"}, OnError: "ignore"}, "hooks.post_restore.on_error"},
+	}
+	for _, tc := range cases {
+		cfg := baseValidConfig()
+		hook := tc.hook
+		cfg.Databases[0].Hooks = &HooksConfig{PostRestore: &hook}
+		err := cfg.Validate()
+		if tc.want == "" {
+			if err != nil {
+				t.Fatalf("%+v: expected valid, got %v", tc.hook, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: expected %q error, got %v", tc.hook, tc.want, err)
+		}
+	}
+}
@@ -94,6 +94,9 @@ func buildEmailBody(event Event) string {
 		"dest: " + event.Dest,
 		"duration: " + event.Duration,
 	}
+	if event.Host != "" {
+		lines = append(lines, "host: "+event.Host)
+	}
 	if event.Error != "" {
 		lines = append(lines, "error: "+event.Error)
 	}
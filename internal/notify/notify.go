@@ -20,6 +20,7 @@ type Event struct {
 	Status   string `json:"status"`
 	Bytes    int64  `json:"bytes"`
 	Dest     string `json:"dest"`
+	Host     string `json:"host,omitempty"`
 	Duration string `json:"duration"`
 	Error    string `json:"error,omitempty"`
 }
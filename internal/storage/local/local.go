@@ -110,3 +110,17 @@ func (s *Storage) Delete(_ context.Context, key string) error {
 	}
 	return nil
 }
+
+func (s *Storage) OpenReader(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	p := filepath.Join(s.base, filepath.FromSlash(key))
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open %s: %w", key, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, fmt.Errorf("stat %s: %w", key, err)
+	}
+	return f, fi.Size(), nil
+}
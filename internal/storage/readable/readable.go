@@ -0,0 +1,11 @@
+package readable
+
+import (
+	"context"
+	"io"
+)
+
+type Readable interface {
+	// OpenReader streams a stored object back. size is -1 when the backend cannot tell.
+	OpenReader(ctx context.Context, key string) (rc io.ReadCloser, size int64, err error)
+}
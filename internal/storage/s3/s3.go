@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go"
+	"github.com/dev-tams/backupkit/internal/storage/prunable"
 )
 
 type Storage struct {
@@ -132,3 +135,96 @@ func (w *uploadWriter) Close() error {
 }
 
 func (w *uploadWriter) Location() string { return w.loc }
+
+func (s *Storage) OpenReader(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	fullKey := key
+	if s.prefix != "" {
+		fullKey = path.Join(s.prefix, key)
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(fullKey),
+	})
+	if err != nil {
+		if apiErr, ok := err.(smithy.APIError); ok {
+			if apiErr.ErrorCode() == "NoSuchKey" {
+				return nil, 0, fmt.Errorf("s3 getobject failed: %s: %w", key, fs.ErrNotExist)
+			}
+			return nil, 0, fmt.Errorf("s3 getobject failed: %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+		}
+		return nil, 0, fmt.Errorf("s3 getobject failed: %w", err)
+	}
+
+	size := int64(-1)
+	if out.ContentLength != nil {
+		size = *out.ContentLength
+	}
+	return out.Body, size, nil
+}
+
+var _ prunable.Prunable = (*Storage)(nil)
+
+// BasePath is empty; S3 objects have no local path.
+func (s *Storage) BasePath() string { return "" }
+
+// List returns the objects directly under prefix, like a directory listing;
+// keys are relative to the configured s3.prefix.
+func (s *Storage) List(ctx context.Context, prefix string) ([]prunable.ObjectInfo, error) {
+	fullPrefix := strings.Trim(prefix, "/") + "/"
+	if s.prefix != "" {
+		fullPrefix = path.Join(s.prefix, prefix) + "/"
+	}
+
+	var out []prunable.ObjectInfo
+	p := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(fullPrefix),
+		Delimiter: aws.String("/"),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			if apiErr, ok := err.(smithy.APIError); ok {
+				return nil, fmt.Errorf("s3 listobjects failed: %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+			}
+			return nil, fmt.Errorf("s3 listobjects failed: %w", err)
+		}
+		for _, o := range page.Contents {
+			key := aws.ToString(o.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			var size int64
+			if o.Size != nil {
+				size = *o.Size
+			}
+			var mod time.Time
+			if o.LastModified != nil {
+				mod = *o.LastModified
+			}
+			out = append(out, prunable.ObjectInfo{Key: key, Size: size, ModTime: mod})
+		}
+	}
+	return out, nil
+}
+
+// Delete removes key; deleting a missing key is not an error, as on S3 itself.
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	fullKey := key
+	if s.prefix != "" {
+		fullKey = path.Join(s.prefix, key)
+	}
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(fullKey),
+	})
+	if err != nil {
+		if apiErr, ok := err.(smithy.APIError); ok {
+			return fmt.Errorf("s3 deleteobject failed: %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+		}
+		return fmt.Errorf("s3 deleteobject failed: %w", err)
+	}
+	return nil
+}